@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/term"
+
+	"github.com/hiway/chirp/pkg/chirp"
+)
+
+// doctor prints what chirp would use in this environment: the config, the
+// shell, whether stdin and stdout are terminals, and which audio backends
+// work. It returns the process exit code.
+func doctor(log zerolog.Logger) int {
+	// Only warnings matter here; the report goes to stdout
+	if !debug {
+		log = log.Level(zerolog.WarnLevel)
+	}
+
+	cfg, err := loadConfig(log)
+	switch {
+	case err != nil:
+		fmt.Printf("config:  %s: %v\n", configFile, err)
+		return 1
+	case configFile != "":
+		fmt.Printf("config:  %s (%d samples, %d queues)\n", configFile, len(cfg.Samples), len(cfg.Queues))
+	default:
+		fmt.Printf("config:  built-in default (%d samples, %d queues)\n", len(cfg.Samples), len(cfg.Queues))
+	}
+
+	fmt.Printf("command: %s\n", strings.Join(chirp.Command(cfg), " "))
+	fmt.Printf("stdin:   %s\n", ttyStatus(os.Stdin))
+	fmt.Printf("stdout:  %s\n", ttyStatus(os.Stdout))
+
+	fmt.Println("audio:")
+	selected := ""
+	for _, name := range cfg.Backends() {
+		if err := chirp.CheckBackend(name, cfg, log); err != nil {
+			fmt.Printf("  %-5s  unavailable: %v\n", name, err)
+			continue
+		}
+		fmt.Printf("  %-5s  ok\n", name)
+		if selected == "" {
+			selected = name
+		}
+	}
+	if selected == "" {
+		fmt.Println("backend: none available, sounds will be discarded")
+	} else {
+		fmt.Printf("backend: %s\n", selected)
+	}
+	return 0
+}
+
+// ttyStatus describes whether f is a terminal.
+func ttyStatus(f *os.File) string {
+	if term.IsTerminal(int(f.Fd())) {
+		return "terminal"
+	}
+	return "not a terminal"
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -16,12 +17,37 @@ import (
 var (
 	configFile string
 	debug      bool
+	audioOut   string
+	shell      string
+	subcommand string // "doctor", "pipe" or empty to wrap a command
 )
 
 func init() {
 	flag.StringVar(&configFile, "config", "", "path to config file (optional)")
 	flag.BoolVar(&debug, "debug", false, "enable debug logging")
+	flag.StringVar(&audioOut, "audio-out", "", "write sounds to a WAV file instead of the sound card")
+	flag.StringVar(&shell, "shell", "", "shell to run (default $SHELL)")
+	flag.Usage = func() {
+		name := os.Args[0]
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [-- command [args...]]\n       %s pipe [flags]\n       %s doctor [flags]\n", name, name, name)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+
+	// Flags may also follow a subcommand, as in "chirp doctor -config x".
+	// After "--", a subcommand name is a command to wrap instead.
+	afterDashes := os.Args[len(os.Args)-flag.NArg()-1] == "--"
+	if flag.NArg() > 0 && !afterDashes {
+		switch flag.Arg(0) {
+		case "doctor", "pipe":
+			subcommand = flag.Arg(0)
+			flag.CommandLine.Parse(flag.Args()[1:])
+			if flag.NArg() > 0 {
+				flag.Usage()
+				os.Exit(2)
+			}
+		}
+	}
 }
 
 func main() {
@@ -36,19 +62,15 @@ func main() {
 		Timestamp().
 		Logger()
 
+	if subcommand == "doctor" {
+		os.Exit(doctor(log))
+	}
+
 	// Load configuration
-	var cfg *config.Config
-	var err error
-	if configFile != "" {
-		cfg, err = config.LoadConfig(configFile, log)
-		if err != nil {
-			log.Error().Err(err).Str("path", configFile).Msg("Failed to load config file")
-			os.Exit(1)
-		}
-		log.Info().Str("path", configFile).Msg("Loaded configuration file")
-	} else {
-		cfg = chirp.DefaultConfig()
-		log.Info().Msg("Using default configuration")
+	cfg, err := loadConfig(log)
+	if err != nil {
+		log.Error().Err(err).Str("path", configFile).Msg("Failed to load config file")
+		os.Exit(1)
 	}
 
 	// Create chirp instance
@@ -70,9 +92,50 @@ func main() {
 		cancel()
 	}()
 
-	// Start chirp
-	if err := c.Start(ctx); err != nil {
+	if subcommand == "pipe" {
+		if err := c.Pipe(ctx, os.Stdin, os.Stdout); err != nil {
+			log.Error().Err(err).Msg("Chirp exited with error")
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Start chirp and exit with the wrapped command's status
+	status, err := c.Start(ctx)
+	if err != nil {
 		log.Error().Err(err).Msg("Chirp exited with error")
 		os.Exit(1)
 	}
+	if status.ShellCode() != 0 {
+		log.Debug().Stringer("status", status).Msg("Wrapped command failed")
+	}
+	os.Exit(status.ShellCode())
+}
+
+// loadConfig loads the config file given with -config, or the default
+// configuration, and applies the command line overrides.
+func loadConfig(log zerolog.Logger) (*config.Config, error) {
+	var cfg *config.Config
+	if configFile != "" {
+		var err error
+		cfg, err = config.LoadConfig(configFile, log)
+		if err != nil {
+			return nil, err
+		}
+		log.Info().Str("path", configFile).Msg("Loaded configuration file")
+	} else {
+		cfg = chirp.DefaultConfig()
+		log.Info().Msg("Using default configuration")
+	}
+	if audioOut != "" {
+		cfg.AudioOut = audioOut
+		cfg.AudioBackend = nil // Select the wav backend
+	}
+	if shell != "" {
+		cfg.Shell = shell
+	}
+	if subcommand == "" && flag.NArg() > 0 {
+		cfg.Command = flag.Args()
+	}
+	return cfg, nil
 }
@@ -6,13 +6,20 @@ require (
 	github.com/BurntSushi/toml v1.5.0
 	github.com/creack/pty v1.1.24
 	github.com/ebitengine/oto/v3 v3.3.3
+	github.com/jfreymuth/oggvorbis v1.0.5
+	github.com/mewkiz/flac v1.0.12
+	github.com/rivo/uniseg v0.4.7
 	github.com/rs/zerolog v1.34.0
 	golang.org/x/term v0.31.0
+	golang.org/x/text v0.24.0
 )
 
 require (
 	github.com/ebitengine/purego v0.8.0 // indirect
+	github.com/icza/bitio v1.1.0 // indirect
+	github.com/jfreymuth/vorbis v1.0.2 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
+	github.com/mewkiz/pkg v0.0.0-20230226050401-4010bf0fec14 // indirect
 	golang.org/x/sys v0.32.0 // indirect
 )
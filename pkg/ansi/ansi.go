@@ -0,0 +1,339 @@
+package ansi
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxParams is the number of CSI parameter bytes kept per sequence
+	MaxParams = 256
+	// MaxData is the number of OSC/DCS payload bytes kept per sequence
+	MaxData = 4096
+)
+
+// Kind identifies the type of an escape sequence.
+type Kind int
+
+const (
+	// ESC is a plain escape sequence, e.g. ESC c
+	ESC Kind = iota
+	// CSI is a control sequence, e.g. ESC [ 1 ; 31 m
+	CSI
+	// OSC is an operating system command, e.g. ESC ] 0 ; title BEL
+	OSC
+	// DCS is a device control string
+	DCS
+	// SOS is a start of string sequence
+	SOS
+	// PM is a privacy message
+	PM
+	// APC is an application program command
+	APC
+	// SS3 is a single shift 3, ESC O and one character, as sent by F1-F4
+	// and by the arrow keys in application mode
+	SS3
+)
+
+var kindNames = map[Kind]string{
+	ESC: "esc",
+	CSI: "csi",
+	OSC: "osc",
+	DCS: "dcs",
+	SOS: "sos",
+	PM:  "pm",
+	APC: "apc",
+	SS3: "ss3",
+}
+
+// String returns the lower-case name of the kind.
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Sequence is a parsed escape sequence.
+type Sequence struct {
+	Kind         Kind
+	Params       string // CSI parameter bytes, including any private marker such as "?"
+	Intermediate string // Intermediate bytes before the final byte
+	Final        byte   // Final byte for ESC and CSI sequences
+	Data         string // Payload of OSC, DCS, SOS, PM and APC strings
+}
+
+// Command returns the numeric command of an OSC sequence, the part of the
+// payload before the first ';'.
+func (s Sequence) Command() string {
+	cmd, _, _ := strings.Cut(s.Data, ";")
+	return cmd
+}
+
+// String returns the sequence in the notation used by queue escape
+// patterns, e.g. "csi:1;31m", "osc:0" or "esc:c".
+func (s Sequence) String() string {
+	switch s.Kind {
+	case ESC, SS3:
+		return s.Kind.String() + ":" + s.Intermediate + string(s.Final)
+	case CSI:
+		return s.Kind.String() + ":" + s.Params + s.Intermediate + string(s.Final)
+	case OSC:
+		return s.Kind.String() + ":" + s.Command()
+	default:
+		return s.Kind.String()
+	}
+}
+
+// Spec is a pattern that selects escape sequences.
+//
+// A spec is a kind name, optionally followed by ':' and a detail:
+//
+//	csi       any control sequence
+//	csi:J     control sequences with final byte J, whatever the parameters
+//	csi:2J    control sequences with parameters 2 and final byte J
+//	osc:0     operating system commands with command number 0
+//	esc:c     ESC c
+//	ss3:P     ESC O P, the F1 key
+//	dcs       any device control string (also sos, pm, apc)
+type Spec struct {
+	Kind   Kind
+	Detail string
+}
+
+// ParseSpec parses an escape sequence pattern.
+func ParseSpec(spec string) (Spec, error) {
+	name, detail, _ := strings.Cut(spec, ":")
+	for kind, kindName := range kindNames {
+		if strings.EqualFold(name, kindName) {
+			return Spec{Kind: kind, Detail: detail}, nil
+		}
+	}
+	return Spec{}, fmt.Errorf("unknown escape sequence kind %q", name)
+}
+
+// Matches reports whether the sequence is selected by the spec.
+func (sp Spec) Matches(s Sequence) bool {
+	if sp.Kind != s.Kind {
+		return false
+	}
+	if sp.Detail == "" {
+		return true
+	}
+	switch s.Kind {
+	case ESC, SS3:
+		return sp.Detail == s.Intermediate+string(s.Final)
+	case CSI:
+		tail := s.Intermediate + string(s.Final)
+		return sp.Detail == tail || sp.Detail == s.Params+tail
+	case OSC:
+		return sp.Detail == s.Command()
+	default:
+		return sp.Detail == s.Data
+	}
+}
+
+// String returns the spec in its configuration notation.
+func (sp Spec) String() string {
+	if sp.Detail == "" {
+		return sp.Kind.String()
+	}
+	return sp.Kind.String() + ":" + sp.Detail
+}
+
+type state int
+
+const (
+	stateGround state = iota
+	stateEscape
+	stateEscapeIntermediate
+	stateCSI
+	stateSS3       // ESC O seen, waiting for the shifted character
+	stateString    // Inside an OSC, DCS, SOS, PM or APC payload
+	stateStringEsc // ESC seen inside a string, possibly starting ST
+)
+
+// Parser splits a terminal output stream into text and escape sequences.
+// Its state is kept between calls to Feed, so sequences split across reads
+// are parsed correctly. A Parser is not safe for concurrent use.
+type Parser struct {
+	state state
+	seq   Sequence
+	buf   []byte // Parameter bytes or string payload of the current sequence
+}
+
+// NewParser creates a parser positioned in plain text.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// Feed parses the next chunk of output. It calls text for each run of text
+// outside escape sequences, including control characters such as "\n" and
+// BEL, and seq for each complete escape sequence. The text slice is only
+// valid during the call.
+func (p *Parser) Feed(data []byte, text func([]byte), seq func(Sequence)) {
+	start := -1 // Start of the current text run in data
+	flush := func(end int) {
+		if start >= 0 && end > start {
+			text(data[start:end])
+		}
+		start = -1
+	}
+
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch p.state {
+		case stateGround:
+			if b == 0x1b {
+				flush(i)
+				p.begin()
+				continue
+			}
+			if start < 0 {
+				start = i
+			}
+
+		case stateEscape:
+			switch {
+			case b == '[':
+				p.seq.Kind = CSI
+				p.state = stateCSI
+			case b == ']':
+				p.beginString(OSC)
+			case b == 'P':
+				p.beginString(DCS)
+			case b == 'X':
+				p.beginString(SOS)
+			case b == '^':
+				p.beginString(PM)
+			case b == '_':
+				p.beginString(APC)
+			case b == 'O':
+				p.seq.Kind = SS3
+				p.state = stateSS3
+			case b >= 0x20 && b <= 0x2f:
+				p.seq.Intermediate += string(b)
+				p.state = stateEscapeIntermediate
+			case b >= 0x30 && b <= 0x7e:
+				p.seq.Final = b
+				p.emit(seq)
+			default:
+				p.control(b, text)
+			}
+
+		case stateEscapeIntermediate:
+			switch {
+			case b >= 0x20 && b <= 0x2f:
+				if len(p.seq.Intermediate) < MaxParams {
+					p.seq.Intermediate += string(b)
+				}
+			case b >= 0x30 && b <= 0x7e:
+				p.seq.Final = b
+				p.emit(seq)
+			default:
+				p.control(b, text)
+			}
+
+		case stateSS3:
+			if b >= 0x20 && b <= 0x7e {
+				p.seq.Final = b
+				p.emit(seq)
+			} else {
+				p.control(b, text)
+			}
+
+		case stateCSI:
+			switch {
+			case b >= 0x30 && b <= 0x3f && p.seq.Intermediate == "":
+				if len(p.buf) < MaxParams {
+					p.buf = append(p.buf, b)
+				}
+			case b >= 0x20 && b <= 0x2f:
+				if len(p.seq.Intermediate) < MaxParams {
+					p.seq.Intermediate += string(b)
+				}
+			case b >= 0x40 && b <= 0x7e:
+				p.seq.Params = string(p.buf)
+				p.seq.Final = b
+				p.emit(seq)
+			default:
+				p.control(b, text)
+			}
+
+		case stateString:
+			switch {
+			case b == 0x1b:
+				p.state = stateStringEsc
+			case b == 0x07 && p.seq.Kind == OSC:
+				// BEL is a common alternative terminator for OSC
+				p.seq.Data = string(p.buf)
+				p.emit(seq)
+			case b == 0x18 || b == 0x1a:
+				// CAN and SUB abort the string
+				p.state = stateGround
+			default:
+				if len(p.buf) < MaxData {
+					p.buf = append(p.buf, b)
+				}
+			}
+
+		case stateStringEsc:
+			if b == '\\' {
+				p.seq.Data = string(p.buf)
+				p.emit(seq)
+				continue
+			}
+			// Not a string terminator: the string is abandoned and the ESC
+			// starts a new sequence, so b is reprocessed as the byte after it
+			p.begin()
+			i--
+		}
+	}
+	flush(len(data))
+}
+
+// begin starts a new escape sequence after ESC.
+func (p *Parser) begin() {
+	p.seq = Sequence{}
+	p.buf = p.buf[:0]
+	p.state = stateEscape
+}
+
+// beginString starts collecting the payload of a string sequence.
+func (p *Parser) beginString(kind Kind) {
+	p.seq.Kind = kind
+	p.state = stateString
+}
+
+// emit reports the current sequence and returns to plain text.
+func (p *Parser) emit(seq func(Sequence)) {
+	p.state = stateGround
+	if seq != nil {
+		seq(p.seq)
+	}
+}
+
+// control handles a control character inside an escape or control
+// sequence. CAN and SUB cancel the sequence, ESC restarts it, and any
+// other control character is passed through as text.
+func (p *Parser) control(b byte, text func([]byte)) {
+	switch b {
+	case 0x18, 0x1a:
+		p.state = stateGround
+	case 0x1b:
+		p.begin()
+	default:
+		if b < 0x20 || b == 0x7f {
+			text([]byte{b})
+		} else {
+			// Unexpected byte: drop the sequence
+			p.state = stateGround
+		}
+	}
+}
+
+// Reset returns the parser to plain text, discarding any partial sequence.
+func (p *Parser) Reset() {
+	p.state = stateGround
+	p.buf = p.buf[:0]
+}
@@ -0,0 +1,114 @@
+package ansi
+
+import (
+	"slices"
+	"testing"
+)
+
+// parse feeds the chunks to a new parser and returns its events: "t:"
+// followed by text, with adjacent runs joined, or "s:" followed by the
+// sequence and its payload.
+func parse(chunks ...string) []string {
+	var events []string
+	p := NewParser()
+	for _, c := range chunks {
+		p.Feed([]byte(c), func(text []byte) {
+			if n := len(events); n > 0 && events[n-1][:2] == "t:" {
+				events[n-1] += string(text)
+				return
+			}
+			events = append(events, "t:"+string(text))
+		}, func(seq Sequence) {
+			ev := "s:" + seq.String()
+			if seq.Data != "" {
+				ev += "=" + seq.Data
+			}
+			events = append(events, ev)
+		})
+	}
+	return events
+}
+
+func TestParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"plain text", "hello\n", []string{"t:hello\n"}},
+		{"sgr", "a\x1b[1;31mb", []string{"t:a", "s:csi:1;31m", "t:b"}},
+		{"private csi", "\x1b[?25l", []string{"s:csi:?25l"}},
+		{"csi intermediate", "\x1b[2 q", []string{"s:csi:2 q"}},
+		{"esc", "\x1bcx", []string{"s:esc:c", "t:x"}},
+		{"esc intermediate", "\x1b(Bx", []string{"s:esc:(B", "t:x"}},
+		{"ss3", "\x1bOAx", []string{"s:ss3:A", "t:x"}},
+		{"osc bel", "\x1b]0;title\x07x", []string{"s:osc:0=0;title", "t:x"}},
+		{"osc st", "\x1b]2;hi\x1b\\x", []string{"s:osc:2=2;hi", "t:x"}},
+		{"dcs st", "\x1bPq#0\x1b\\x", []string{"s:dcs=q#0", "t:x"}},
+		{"bel outside osc", "a\x07b", []string{"t:a\x07b"}},
+		{"can aborts csi", "\x1b[12\x18x", []string{"t:x"}},
+		{"sub aborts csi", "\x1b[12\x1ax", []string{"t:x"}},
+		{"can aborts osc", "\x1b]0;ab\x18x", []string{"t:x"}},
+		{"sub aborts osc", "\x1b]0;ab\x1ax", []string{"t:x"}},
+		{"esc restarts csi", "\x1b[1\x1b[2Jx", []string{"s:csi:2J", "t:x"}},
+		{"esc ends osc without st", "\x1b]0;ab\x1b[Kx", []string{"s:csi:K", "t:x"}},
+		{"control inside csi", "\x1b[1\n2m", []string{"t:\n", "s:csi:12m"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parse(tt.input); !slices.Equal(got, tt.want) {
+				t.Fatalf("single read: got %q, want %q", got, tt.want)
+			}
+			for i := 1; i < len(tt.input); i++ {
+				if got := parse(tt.input[:i], tt.input[i:]); !slices.Equal(got, tt.want) {
+					t.Errorf("split at %d: got %q, want %q", i, got, tt.want)
+				}
+			}
+			var bytes []string
+			for i := range len(tt.input) {
+				bytes = append(bytes, tt.input[i:i+1])
+			}
+			if got := parse(bytes...); !slices.Equal(got, tt.want) {
+				t.Errorf("byte at a time: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpecMatches(t *testing.T) {
+	clear := Sequence{Kind: CSI, Params: "2", Final: 'J'}
+	title := Sequence{Kind: OSC, Data: "0;my title"}
+	reset := Sequence{Kind: ESC, Final: 'c'}
+	f1 := Sequence{Kind: SS3, Final: 'P'}
+
+	tests := []struct {
+		spec string
+		seq  Sequence
+		want bool
+	}{
+		{"csi", clear, true},
+		{"csi:J", clear, true},
+		{"csi:2J", clear, true},
+		{"csi:1J", clear, false},
+		{"CSI:J", clear, true},
+		{"osc:0", title, true},
+		{"osc:2", title, false},
+		{"esc:c", reset, true},
+		{"osc", reset, false},
+		{"ss3:P", f1, true},
+		{"ss3:Q", f1, false},
+		{"esc:P", f1, false},
+	}
+	for _, tt := range tests {
+		sp, err := ParseSpec(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseSpec(%q) = %v", tt.spec, err)
+		}
+		if got := sp.Matches(tt.seq); got != tt.want {
+			t.Errorf("%q matches %v = %v, want %v", tt.spec, tt.seq, got, tt.want)
+		}
+	}
+	if _, err := ParseSpec("bogus:J"); err == nil {
+		t.Error("ParseSpec(\"bogus:J\") succeeded, want an error")
+	}
+}
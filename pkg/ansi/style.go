@@ -0,0 +1,285 @@
+package ansi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Attr is a set of SGR text attributes.
+type Attr uint16
+
+// SGR text attributes.
+const (
+	Bold Attr = 1 << iota
+	Dim
+	Italic
+	Underline
+	Blink
+	Reverse
+	Hidden
+	Strike
+)
+
+var attrNames = map[string]Attr{
+	"bold":      Bold,
+	"dim":       Dim,
+	"italic":    Italic,
+	"underline": Underline,
+	"blink":     Blink,
+	"reverse":   Reverse,
+	"hidden":    Hidden,
+	"strike":    Strike,
+}
+
+// ColorMode describes how a colour was set.
+type ColorMode uint8
+
+const (
+	// ColorDefault is the terminal's default colour
+	ColorDefault ColorMode = iota
+	// ColorIndexed is one of the 256 palette colours; 0-15 are the basic
+	// and bright ANSI colours
+	ColorIndexed
+	// ColorRGB is a 24-bit colour
+	ColorRGB
+)
+
+// Color is a foreground or background colour.
+type Color struct {
+	Mode    ColorMode
+	Index   uint8
+	R, G, B uint8
+}
+
+var colorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// Style is the text style selected by SGR sequences.
+type Style struct {
+	Fg, Bg Color
+	Attrs  Attr
+}
+
+// Update applies an SGR sequence (CSI ... m) to the style. It reports
+// whether the sequence was an SGR sequence.
+func (st *Style) Update(seq Sequence) bool {
+	if seq.Kind != CSI || seq.Final != 'm' || seq.Intermediate != "" ||
+		strings.ContainsAny(seq.Params, "<=>?") {
+		return false
+	}
+
+	// Colon sub-parameters (38:5:1) are treated like semicolons (38;5;1)
+	params := strings.Split(strings.ReplaceAll(seq.Params, ":", ";"), ";")
+	num := func(i int) int {
+		if i >= len(params) {
+			return -1
+		}
+		if params[i] == "" {
+			return 0 // An omitted parameter defaults to 0
+		}
+		n, err := strconv.Atoi(params[i])
+		if err != nil {
+			return -1
+		}
+		return n
+	}
+
+	for i := 0; i < len(params); i++ {
+		switch n := num(i); {
+		case n == 0:
+			*st = Style{}
+		case n == 1:
+			st.Attrs |= Bold
+		case n == 2:
+			st.Attrs |= Dim
+		case n == 3:
+			st.Attrs |= Italic
+		case n == 4:
+			st.Attrs |= Underline
+		case n == 5 || n == 6:
+			st.Attrs |= Blink
+		case n == 7:
+			st.Attrs |= Reverse
+		case n == 8:
+			st.Attrs |= Hidden
+		case n == 9:
+			st.Attrs |= Strike
+		case n == 22:
+			st.Attrs &^= Bold | Dim
+		case n == 23:
+			st.Attrs &^= Italic
+		case n == 24:
+			st.Attrs &^= Underline
+		case n == 25:
+			st.Attrs &^= Blink
+		case n == 27:
+			st.Attrs &^= Reverse
+		case n == 28:
+			st.Attrs &^= Hidden
+		case n == 29:
+			st.Attrs &^= Strike
+		case n >= 30 && n <= 37:
+			st.Fg = Color{Mode: ColorIndexed, Index: uint8(n - 30)}
+		case n == 39:
+			st.Fg = Color{}
+		case n >= 40 && n <= 47:
+			st.Bg = Color{Mode: ColorIndexed, Index: uint8(n - 40)}
+		case n == 49:
+			st.Bg = Color{}
+		case n >= 90 && n <= 97:
+			st.Fg = Color{Mode: ColorIndexed, Index: uint8(n - 90 + 8)}
+		case n >= 100 && n <= 107:
+			st.Bg = Color{Mode: ColorIndexed, Index: uint8(n - 100 + 8)}
+		case n == 38 || n == 48:
+			c, used := extendedColor(num, i+1)
+			if n == 38 {
+				st.Fg = c
+			} else {
+				st.Bg = c
+			}
+			i += used
+		}
+	}
+	return true
+}
+
+// extendedColor parses the arguments of SGR 38 or 48 starting at i. It
+// returns the colour and the number of arguments consumed.
+func extendedColor(num func(int) int, i int) (Color, int) {
+	switch num(i) {
+	case 5:
+		if n := num(i + 1); n >= 0 && n <= 255 {
+			return Color{Mode: ColorIndexed, Index: uint8(n)}, 2
+		}
+		return Color{}, 2
+	case 2:
+		r, g, b := num(i+1), num(i+2), num(i+3)
+		if r < 0 || g < 0 || b < 0 {
+			return Color{}, 4
+		}
+		return Color{Mode: ColorRGB, R: clamp8(r), G: clamp8(g), B: clamp8(b)}, 4
+	}
+	return Color{}, 1
+}
+
+// clamp8 limits a colour component to 0-255.
+func clamp8(n int) uint8 {
+	return uint8(min(max(n, 0), 255))
+}
+
+// colorSpec selects a colour by name, palette index or RGB value.
+type colorSpec struct {
+	mode    ColorMode
+	index   uint8
+	anyTone bool // Basic colour name: matches the normal and bright variant
+	r, g, b uint8
+}
+
+func (c colorSpec) matches(col Color) bool {
+	switch c.mode {
+	case ColorDefault:
+		return col.Mode == ColorDefault
+	case ColorRGB:
+		return col.Mode == ColorRGB && col.R == c.r && col.G == c.g && col.B == c.b
+	}
+	if col.Mode != ColorIndexed {
+		return false
+	}
+	if c.anyTone && col.Index < 16 {
+		return col.Index%8 == c.index
+	}
+	return col.Index == c.index
+}
+
+// parseColor parses a colour name such as "red", "bright_red", "default",
+// a palette index such as "208", or an RGB value such as "#ff8700".
+func parseColor(s string) (colorSpec, error) {
+	s = strings.ToLower(s)
+	if s == "default" {
+		return colorSpec{mode: ColorDefault}, nil
+	}
+	if strings.HasPrefix(s, "#") && len(s) == 7 {
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err != nil {
+			return colorSpec{}, fmt.Errorf("invalid RGB colour %q", s)
+		}
+		return colorSpec{mode: ColorRGB, r: uint8(v >> 16), g: uint8(v >> 8), b: uint8(v)}, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > 255 {
+			return colorSpec{}, fmt.Errorf("colour index %d out of range 0-255", n)
+		}
+		return colorSpec{mode: ColorIndexed, index: uint8(n)}, nil
+	}
+	name, bright := strings.CutPrefix(s, "bright_")
+	for i, c := range colorNames {
+		if name == c {
+			if bright {
+				return colorSpec{mode: ColorIndexed, index: uint8(i + 8)}, nil
+			}
+			return colorSpec{mode: ColorIndexed, index: uint8(i), anyTone: true}, nil
+		}
+	}
+	return colorSpec{}, fmt.Errorf("unknown colour %q", s)
+}
+
+// StyleSpec is a pattern that selects text styles, written as terms joined
+// by '+', e.g. "fg:red" or "bold+fg:yellow". Each term is an attribute
+// (bold, dim, italic, underline, blink, reverse, hidden, strike) or a
+// colour, "fg:<colour>" or "bg:<colour>". A colour is a name (red,
+// bright_red, default), a palette index (0-255) or an RGB value (#rrggbb).
+// A basic colour name also matches its bright variant.
+type StyleSpec struct {
+	text   string
+	attrs  Attr
+	fg, bg *colorSpec
+}
+
+// ParseStyleSpec parses a style pattern.
+func ParseStyleSpec(spec string) (StyleSpec, error) {
+	sp := StyleSpec{text: spec}
+	for _, term := range strings.Split(spec, "+") {
+		term = strings.TrimSpace(term)
+		if attr, ok := attrNames[strings.ToLower(term)]; ok {
+			sp.attrs |= attr
+			continue
+		}
+		which, value, ok := strings.Cut(term, ":")
+		if !ok {
+			return StyleSpec{}, fmt.Errorf("unknown style term %q", term)
+		}
+		c, err := parseColor(value)
+		if err != nil {
+			return StyleSpec{}, err
+		}
+		switch strings.ToLower(which) {
+		case "fg":
+			sp.fg = &c
+		case "bg":
+			sp.bg = &c
+		default:
+			return StyleSpec{}, fmt.Errorf("unknown style term %q", term)
+		}
+	}
+	return sp, nil
+}
+
+// Matches reports whether the style has every attribute and colour the
+// spec asks for.
+func (sp StyleSpec) Matches(st Style) bool {
+	if st.Attrs&sp.attrs != sp.attrs {
+		return false
+	}
+	if sp.fg != nil && !sp.fg.matches(st.Fg) {
+		return false
+	}
+	if sp.bg != nil && !sp.bg.matches(st.Bg) {
+		return false
+	}
+	return true
+}
+
+// String returns the spec as it was written.
+func (sp StyleSpec) String() string {
+	return sp.text
+}
@@ -0,0 +1,91 @@
+package ansi
+
+import "testing"
+
+func sgr(params string) Sequence {
+	return Sequence{Kind: CSI, Params: params, Final: 'm'}
+}
+
+func TestStyleUpdate(t *testing.T) {
+	red := Color{Mode: ColorIndexed, Index: 1}
+	tests := []struct {
+		name   string
+		start  Style
+		params string
+		want   Style
+	}{
+		{"bold red", Style{}, "1;31", Style{Fg: red, Attrs: Bold}},
+		{"reset", Style{Fg: red, Attrs: Bold}, "0", Style{}},
+		{"empty resets", Style{Fg: red, Attrs: Bold}, "", Style{}},
+		{"normal intensity", Style{Attrs: Bold | Dim | Italic}, "22", Style{Attrs: Italic}},
+		{"bright fg", Style{}, "91", Style{Fg: Color{Mode: ColorIndexed, Index: 9}}},
+		{"bright bg", Style{}, "104", Style{Bg: Color{Mode: ColorIndexed, Index: 12}}},
+		{"default fg", Style{Fg: red}, "39", Style{}},
+		{"256 fg", Style{}, "38;5;208", Style{Fg: Color{Mode: ColorIndexed, Index: 208}}},
+		{"256 bg", Style{}, "48;5;1", Style{Bg: red}},
+		{"256 colon", Style{}, "38:5:208", Style{Fg: Color{Mode: ColorIndexed, Index: 208}}},
+		{"256 then bold", Style{}, "38;5;208;1", Style{Fg: Color{Mode: ColorIndexed, Index: 208}, Attrs: Bold}},
+		{"256 out of range", Style{Fg: red}, "38;5;300", Style{}},
+		{"rgb fg", Style{}, "38;2;255;135;0", Style{Fg: Color{Mode: ColorRGB, R: 255, G: 135, B: 0}}},
+		{"rgb bg then underline", Style{}, "48;2;1;2;3;4", Style{Bg: Color{Mode: ColorRGB, R: 1, G: 2, B: 3}, Attrs: Underline}},
+		{"rgb clamped", Style{}, "38;2;300;0;999", Style{Fg: Color{Mode: ColorRGB, R: 255, G: 0, B: 255}}},
+		{"rgb missing", Style{Fg: red}, "38;2;1", Style{}},
+	}
+	for _, tt := range tests {
+		st := tt.start
+		if !st.Update(sgr(tt.params)) {
+			t.Errorf("%s: Update reported a non-SGR sequence", tt.name)
+			continue
+		}
+		if st != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, st, tt.want)
+		}
+	}
+}
+
+func TestStyleUpdateIgnoresOtherSequences(t *testing.T) {
+	for _, seq := range []Sequence{
+		{Kind: CSI, Params: "2", Final: 'J'},
+		{Kind: CSI, Params: ">4;2", Final: 'm'}, // Private, e.g. modifyOtherKeys
+		{Kind: OSC, Data: "0;m"},
+	} {
+		st := Style{Attrs: Bold}
+		if st.Update(seq) || st.Attrs != Bold {
+			t.Errorf("Update(%v) changed the style or reported SGR", seq)
+		}
+	}
+}
+
+func TestStyleSpecMatches(t *testing.T) {
+	yellow := Color{Mode: ColorIndexed, Index: 3}
+	brightYellow := Color{Mode: ColorIndexed, Index: 11}
+	tests := []struct {
+		spec  string
+		style Style
+		want  bool
+	}{
+		{"bold+fg:yellow", Style{Fg: yellow, Attrs: Bold}, true},
+		{"bold+fg:yellow", Style{Fg: brightYellow, Attrs: Bold | Underline}, true},
+		{"bold+fg:yellow", Style{Fg: yellow}, false},
+		{"fg:bright_yellow", Style{Fg: yellow}, false},
+		{"fg:bright_yellow", Style{Fg: brightYellow}, true},
+		{"fg:208", Style{Fg: Color{Mode: ColorIndexed, Index: 208}}, true},
+		{"fg:#ff8700", Style{Fg: Color{Mode: ColorRGB, R: 0xff, G: 0x87}}, true},
+		{"bg:default", Style{Fg: yellow}, true},
+		{"bg:default", Style{Bg: yellow}, false},
+	}
+	for _, tt := range tests {
+		sp, err := ParseStyleSpec(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseStyleSpec(%q) = %v", tt.spec, err)
+		}
+		if got := sp.Matches(tt.style); got != tt.want {
+			t.Errorf("%q matches %+v = %v, want %v", tt.spec, tt.style, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"fg:purple", "fg:256", "shiny", "xx:red"} {
+		if _, err := ParseStyleSpec(bad); err == nil {
+			t.Errorf("ParseStyleSpec(%q) succeeded, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// SampleRate is the number of frames per second of decoded audio
+	SampleRate = 48000
+	// ChannelCount is the number of interleaved channels of decoded audio
+	ChannelCount = 2
+)
+
+// Buffer holds decoded audio as interleaved stereo 16-bit samples at
+// SampleRate, the native format of the players.
+type Buffer struct {
+	Samples []int16
+}
+
+// Frames returns the number of sample frames in the buffer.
+func (b *Buffer) Frames() int {
+	return len(b.Samples) / ChannelCount
+}
+
+// Duration returns the playing time of the buffer.
+func (b *Buffer) Duration() time.Duration {
+	return time.Duration(b.Frames()) * time.Second / SampleRate
+}
+
+// Repitched returns the buffer played back factor times faster, which
+// raises its pitch by that factor and shortens it to match.
+func (b *Buffer) Repitched(factor float64) *Buffer {
+	frames := b.Frames()
+	left := make([]float32, frames)
+	right := make([]float32, frames)
+	for i := 0; i < frames; i++ {
+		left[i] = float32(b.Samples[i*ChannelCount]) / 32767
+		right[i] = float32(b.Samples[i*ChannelCount+1]) / 32767
+	}
+
+	left = resampleRatio(left, 1/factor)
+	right = resampleRatio(right, 1/factor)
+
+	out := &Buffer{Samples: make([]int16, len(left)*ChannelCount)}
+	for i := range left {
+		out.Samples[i*ChannelCount] = toInt16(left[i])
+		out.Samples[i*ChannelCount+1] = toInt16(right[i])
+	}
+	return out
+}
+
+// LoadFile decodes an audio file into the native format. The format is
+// chosen by file extension.
+func LoadFile(path string) (*Buffer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
+	}
+	defer f.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".wav", ".wave":
+		return DecodeWAV(f)
+	case ".ogg", ".oga":
+		return DecodeOgg(f)
+	case ".flac":
+		return DecodeFLAC(f)
+	default:
+		return nil, fmt.Errorf("unsupported audio file type %q", ext)
+	}
+}
+
+// convert turns decoded samples into a native Buffer. The input holds
+// interleaved samples in the range -1.0 to 1.0 with the given channel
+// count and rate. Mono is copied to both channels; beyond two channels
+// only the first two are kept.
+func convert(samples []float32, channels, rate int) (*Buffer, error) {
+	if channels <= 0 {
+		return nil, fmt.Errorf("invalid channel count %d", channels)
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", rate)
+	}
+
+	// Split into left and right channels
+	frames := len(samples) / channels
+	left := make([]float32, frames)
+	right := make([]float32, frames)
+	for i := 0; i < frames; i++ {
+		left[i] = samples[i*channels]
+		if channels > 1 {
+			right[i] = samples[i*channels+1]
+		} else {
+			right[i] = left[i]
+		}
+	}
+
+	if rate != SampleRate {
+		left = resample(left, rate, SampleRate)
+		right = resample(right, rate, SampleRate)
+	}
+
+	buf := &Buffer{Samples: make([]int16, len(left)*ChannelCount)}
+	for i := range left {
+		buf.Samples[i*ChannelCount] = toInt16(left[i])
+		buf.Samples[i*ChannelCount+1] = toInt16(right[i])
+	}
+	return buf, nil
+}
+
+// resampleTaps is the number of input samples on each side of the output
+// position used by the interpolation filter.
+const resampleTaps = 16
+
+// resample converts a channel from one rate to another using a
+// Hann-windowed sinc filter. The filter cutoff is lowered when
+// downsampling so nothing aliases.
+func resample(in []float32, from, to int) []float32 {
+	return resampleRatio(in, float64(to)/float64(from))
+}
+
+// resampleRatio resamples a channel to ratio output samples per input
+// sample.
+func resampleRatio(in []float32, ratio float64) []float32 {
+	out := make([]float32, int(math.Ceil(float64(len(in))*ratio)))
+
+	cutoff := math.Min(1, ratio) // Relative to the input Nyquist frequency
+	width := float64(resampleTaps) / cutoff
+	for i := range out {
+		center := float64(i) / ratio
+		lo := int(math.Ceil(center - width))
+		hi := int(math.Floor(center + width))
+		var sum, norm float64
+		for j := lo; j <= hi; j++ {
+			if j < 0 || j >= len(in) {
+				continue
+			}
+			x := float64(j) - center
+			w := cutoff * sinc(cutoff*x) * 0.5 * (1 + math.Cos(math.Pi*x/width))
+			sum += w * float64(in[j])
+			norm += w
+		}
+		if norm != 0 {
+			out[i] = float32(sum / norm)
+		}
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// toInt16 converts a sample in the range -1.0 to 1.0, clipping if needed.
+func toInt16(v float32) int16 {
+	s := math.Round(float64(v) * 32767)
+	if s > 32767 {
+		return 32767
+	}
+	if s < -32768 {
+		return -32768
+	}
+	return int16(s)
+}
@@ -0,0 +1,40 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+)
+
+// DecodeFLAC decodes a FLAC stream into the native format.
+func DecodeFLAC(r io.Reader) (*Buffer, error) {
+	stream, err := flac.New(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FLAC stream: %w", err)
+	}
+	defer stream.Close()
+
+	channels := int(stream.Info.NChannels)
+	scale := float32(int64(1) << (stream.Info.BitsPerSample - 1))
+	samples := make([]float32, 0, int(stream.Info.NSamples)*channels)
+	for {
+		f, err := stream.ParseNext()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode FLAC frame: %w", err)
+		}
+		if len(f.Subframes) != channels {
+			return nil, fmt.Errorf("FLAC frame has %d channels, stream has %d", len(f.Subframes), channels)
+		}
+		for i := 0; i < int(f.BlockSize); i++ {
+			for _, sub := range f.Subframes {
+				samples = append(samples, float32(sub.Samples[i])/scale)
+			}
+		}
+	}
+	return convert(samples, channels, int(stream.Info.SampleRate))
+}
@@ -0,0 +1,17 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jfreymuth/oggvorbis"
+)
+
+// DecodeOgg decodes an Ogg Vorbis stream into the native format.
+func DecodeOgg(r io.Reader) (*Buffer, error) {
+	samples, format, err := oggvorbis.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Ogg Vorbis: %w", err)
+	}
+	return convert(samples, format.Channels, format.SampleRate)
+}
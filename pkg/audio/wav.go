@@ -0,0 +1,182 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WAV format tags.
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xfffe
+)
+
+// wavFormat is the content of a WAV "fmt " chunk.
+type wavFormat struct {
+	tag        uint16
+	channels   int
+	rate       int
+	bits       int
+	blockAlign int
+}
+
+// DecodeWAV decodes a RIFF WAVE stream into the native format. Integer PCM
+// with 8, 16, 24 or 32 bits and IEEE float with 32 or 64 bits are
+// supported, at any sample rate and channel count.
+func DecodeWAV(r io.Reader) (*Buffer, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, errors.New("not a RIFF WAVE file")
+	}
+	riffSize := int64(binary.LittleEndian.Uint32(header[4:8]))
+
+	var format *wavFormat
+	pos := int64(len(header)) // Offset of the next chunk
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if format == nil {
+				return nil, errors.New("WAV file has no fmt chunk")
+			}
+			return nil, errors.New("WAV file has no data chunk")
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		pos += int64(len(chunk))
+
+		switch id {
+		case "fmt ":
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, fmt.Errorf("failed to read WAV fmt chunk: %w", err)
+			}
+			f, err := parseWAVFormat(data)
+			if err != nil {
+				return nil, err
+			}
+			format = f
+		case "data":
+			if format == nil {
+				return nil, errors.New("WAV data chunk before fmt chunk")
+			}
+			var src io.Reader = r
+			if !unsizedData(size, riffSize, pos) {
+				src = io.LimitReader(r, size)
+			}
+			data, err := io.ReadAll(src)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read WAV data: %w", err)
+			}
+			samples, err := format.decode(data)
+			if err != nil {
+				return nil, err
+			}
+			return convert(samples, format.channels, format.rate)
+		default:
+			if _, err := io.CopyN(io.Discard, r, size); err != nil {
+				return nil, fmt.Errorf("failed to skip WAV %q chunk: %w", id, err)
+			}
+		}
+
+		// Chunks are padded to an even size
+		if size%2 == 1 {
+			if _, err := io.CopyN(io.Discard, r, 1); err != nil {
+				return nil, fmt.Errorf("failed to read WAV file: %w", err)
+			}
+			pos++
+		}
+		pos += size
+	}
+}
+
+// unsizedData reports whether a data chunk starting at pos leaves its size
+// unset, as streams written live do, so it runs to the end of the file.
+// That is the case when the size is 0xFFFFFFFF, or when it is zero and the
+// RIFF size is unset too or ends the file at the data chunk header. A zero
+// size followed by further chunks is an empty data chunk.
+func unsizedData(size, riffSize, pos int64) bool {
+	switch {
+	case size == math.MaxUint32:
+		return true
+	case size != 0:
+		return false
+	case riffSize == 0 || riffSize == math.MaxUint32:
+		return true
+	default:
+		return riffSize+8 <= pos
+	}
+}
+
+// parseWAVFormat parses and checks a "fmt " chunk.
+func parseWAVFormat(data []byte) (*wavFormat, error) {
+	if len(data) < 16 {
+		return nil, errors.New("WAV fmt chunk too short")
+	}
+	f := &wavFormat{
+		tag:        binary.LittleEndian.Uint16(data[0:2]),
+		channels:   int(binary.LittleEndian.Uint16(data[2:4])),
+		rate:       int(binary.LittleEndian.Uint32(data[4:8])),
+		blockAlign: int(binary.LittleEndian.Uint16(data[12:14])),
+		bits:       int(binary.LittleEndian.Uint16(data[14:16])),
+	}
+	if f.tag == wavFormatExtensible {
+		if len(data) < 26 {
+			return nil, errors.New("WAV extensible fmt chunk too short")
+		}
+		// The sub-format GUID starts with the actual format tag
+		f.tag = binary.LittleEndian.Uint16(data[24:26])
+	}
+
+	if f.channels == 0 || f.rate == 0 {
+		return nil, errors.New("WAV file has no channels or zero sample rate")
+	}
+	switch {
+	case f.tag == wavFormatPCM && (f.bits == 8 || f.bits == 16 || f.bits == 24 || f.bits == 32):
+	case f.tag == wavFormatFloat && (f.bits == 32 || f.bits == 64):
+	default:
+		return nil, fmt.Errorf("unsupported WAV encoding: format %d with %d bits", f.tag, f.bits)
+	}
+	if f.blockAlign < f.channels*f.bits/8 {
+		f.blockAlign = f.channels * f.bits / 8
+	}
+	return f, nil
+}
+
+// decode converts raw sample data to interleaved floats.
+func (f *wavFormat) decode(data []byte) ([]float32, error) {
+	width := f.bits / 8
+	frames := len(data) / f.blockAlign
+	out := make([]float32, 0, frames*f.channels)
+
+	for i := 0; i < frames; i++ {
+		frame := data[i*f.blockAlign:]
+		for c := 0; c < f.channels; c++ {
+			b := frame[c*width : (c+1)*width]
+			var v float64
+			switch {
+			case f.tag == wavFormatFloat && f.bits == 32:
+				v = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+			case f.tag == wavFormatFloat:
+				v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+			case f.bits == 8:
+				v = (float64(b[0]) - 128) / 128 // 8-bit WAV is unsigned
+			case f.bits == 16:
+				v = float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+			case f.bits == 24:
+				s := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+				v = float64(s) / 8388608
+			default:
+				v = float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+			}
+			out = append(out, float32(v))
+		}
+	}
+	return out, nil
+}
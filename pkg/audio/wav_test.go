@@ -0,0 +1,220 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+// testSamples are stereo frames exactly representable at every bit depth.
+var testSamples = []float64{0, 0, 0.5, -0.5, -1, 0.25, 0.75, -0.125}
+
+// encodeSample encodes one sample in the given WAV format.
+func encodeSample(tag uint16, bits int, v float64) []byte {
+	b := make([]byte, bits/8)
+	switch {
+	case tag == wavFormatFloat && bits == 32:
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+	case tag == wavFormatFloat:
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	case bits == 8:
+		b[0] = byte(v*128 + 128)
+	case bits == 16:
+		binary.LittleEndian.PutUint16(b, uint16(int16(v*32768)))
+	case bits == 24:
+		s := uint32(int32(v * 8388608))
+		b[0], b[1], b[2] = byte(s), byte(s>>8), byte(s>>16)
+	default:
+		binary.LittleEndian.PutUint32(b, uint32(int32(v*2147483648)))
+	}
+	return b
+}
+
+// chunk returns a RIFF chunk with its header and padding.
+func chunk(id string, size uint32, data []byte) []byte {
+	out := append([]byte(id), binary.LittleEndian.AppendUint32(nil, size)...)
+	out = append(out, data...)
+	if len(data)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// fmtChunk returns a stereo "fmt " chunk at the native rate.
+func fmtChunk(tag uint16, bits int) []byte {
+	f := binary.LittleEndian.AppendUint16(nil, tag)
+	f = binary.LittleEndian.AppendUint16(f, 2)
+	f = binary.LittleEndian.AppendUint32(f, SampleRate)
+	f = binary.LittleEndian.AppendUint32(f, uint32(SampleRate*2*bits/8))
+	f = binary.LittleEndian.AppendUint16(f, uint16(2*bits/8))
+	f = binary.LittleEndian.AppendUint16(f, uint16(bits))
+	return chunk("fmt ", uint32(len(f)), f)
+}
+
+// riff wraps chunks in a RIFF WAVE header with the given size.
+func riff(size uint32, chunks ...[]byte) []byte {
+	out := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, size)...)
+	out = append(out, "WAVE"...)
+	for _, c := range chunks {
+		out = append(out, c...)
+	}
+	return out
+}
+
+// wantSamples returns the native samples for vs.
+func wantSamples(vs []float64) []int16 {
+	out := make([]int16, len(vs))
+	for i, v := range vs {
+		out[i] = toInt16(float32(v))
+	}
+	return out
+}
+
+func TestDecodeWAV(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  uint16
+		bits int
+	}{
+		{"pcm8", wavFormatPCM, 8},
+		{"pcm16", wavFormatPCM, 16},
+		{"pcm24", wavFormatPCM, 24},
+		{"pcm32", wavFormatPCM, 32},
+		{"float32", wavFormatFloat, 32},
+		{"float64", wavFormatFloat, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data []byte
+			for _, v := range testSamples {
+				data = append(data, encodeSample(tt.tag, tt.bits, v)...)
+			}
+			body := [][]byte{
+				fmtChunk(tt.tag, tt.bits),
+				chunk("data", uint32(len(data)), data),
+				chunk("LIST", 3, []byte("abc")),
+			}
+			size := uint32(4)
+			for _, c := range body {
+				size += uint32(len(c))
+			}
+
+			buf, err := DecodeWAV(bytes.NewReader(riff(size, body...)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSamples(t, buf.Samples, wantSamples(testSamples))
+		})
+	}
+}
+
+func TestDecodeWAVDataSize(t *testing.T) {
+	var data []byte
+	for _, v := range testSamples {
+		data = append(data, encodeSample(wavFormatPCM, 16, v)...)
+	}
+	fmtc := fmtChunk(wavFormatPCM, 16)
+	list := chunk("LIST", 4, []byte("abcd"))
+	headerSize := uint32(4 + len(fmtc) + 8)
+
+	tests := []struct {
+		name   string
+		wav    []byte
+		frames int
+	}{
+		{
+			"empty data before another chunk",
+			riff(headerSize+uint32(len(list)), fmtc, chunk("data", 0, nil), list),
+			0,
+		},
+		{
+			"unset data size",
+			riff(headerSize+uint32(len(data)), fmtc, chunk("data", math.MaxUint32, data)),
+			len(testSamples) / 2,
+		},
+		{
+			"unset riff and data size",
+			riff(0, fmtc, chunk("data", 0, data)),
+			len(testSamples) / 2,
+		},
+		{
+			"live header ending at the data chunk",
+			riff(headerSize, fmtc, chunk("data", 0, data)),
+			len(testSamples) / 2,
+		},
+	}
+	for _, tt := range tests {
+		buf, err := DecodeWAV(bytes.NewReader(tt.wav))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if buf.Frames() != tt.frames {
+			t.Errorf("%s: got %d frames, want %d", tt.name, buf.Frames(), tt.frames)
+		}
+	}
+}
+
+func TestWAVWriterRoundTrip(t *testing.T) {
+	want := wantSamples(testSamples)
+	var f writeSeeker
+	ww, err := NewWAVWriter(&f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := binary.Write(ww, binary.LittleEndian, want); err != nil {
+		t.Fatal(err)
+	}
+	if err := ww.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := DecodeWAV(bytes.NewReader(f.buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSamples(t, buf.Samples, want)
+}
+
+// checkSamples compares decoded samples, allowing one step of error since
+// decoding scales by 32768 and the native format by 32767.
+func checkSamples(t *testing.T, got, want []int16) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if d := int(got[i]) - int(want[i]); d < -1 || d > 1 {
+			t.Errorf("sample %d = %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+// writeSeeker is an in-memory io.WriteSeeker.
+type writeSeeker struct {
+	buf []byte
+	off int
+}
+
+func (w *writeSeeker) Write(p []byte) (int, error) {
+	if end := w.off + len(p); end > len(w.buf) {
+		w.buf = append(w.buf, make([]byte, end-len(w.buf))...)
+	}
+	copy(w.buf[w.off:], p)
+	w.off += len(p)
+	return len(p), nil
+}
+
+func (w *writeSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		w.off = int(offset)
+	case io.SeekCurrent:
+		w.off += int(offset)
+	case io.SeekEnd:
+		w.off = len(w.buf) + int(offset)
+	}
+	return int64(w.off), nil
+}
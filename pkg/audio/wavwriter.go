@@ -0,0 +1,76 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// wavHeaderSize is the size of the RIFF, fmt and data chunk headers.
+const wavHeaderSize = 44
+
+// WAVWriter writes native format audio to a 16-bit PCM WAV file. The chunk
+// sizes in the header are filled in by Close.
+type WAVWriter struct {
+	w    io.WriteSeeker
+	size int64 // Bytes of sample data written
+}
+
+// NewWAVWriter writes a WAV header for the native format to w.
+func NewWAVWriter(w io.WriteSeeker) (*WAVWriter, error) {
+	ww := &WAVWriter{w: w}
+	if err := ww.writeHeader(); err != nil {
+		return nil, err
+	}
+	return ww, nil
+}
+
+// Write appends little-endian 16-bit interleaved sample data.
+func (ww *WAVWriter) Write(p []byte) (int, error) {
+	n, err := ww.w.Write(p)
+	ww.size += int64(n)
+	return n, err
+}
+
+// Close fills in the chunk sizes. It does not close the underlying writer.
+func (ww *WAVWriter) Close() error {
+	if _, err := ww.w.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to WAV header: %w", err)
+	}
+	if err := ww.writeHeader(); err != nil {
+		return err
+	}
+	if _, err := ww.w.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("failed to seek to end of WAV file: %w", err)
+	}
+	return nil
+}
+
+func (ww *WAVWriter) writeHeader() error {
+	const bytesPerSample = 2
+	size := ww.size
+	if size > math.MaxUint32-wavHeaderSize {
+		size = math.MaxUint32 - wavHeaderSize
+	}
+
+	h := make([]byte, wavHeaderSize)
+	copy(h[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:8], uint32(wavHeaderSize-8+size))
+	copy(h[8:12], "WAVE")
+	copy(h[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:20], 16)
+	binary.LittleEndian.PutUint16(h[20:22], wavFormatPCM)
+	binary.LittleEndian.PutUint16(h[22:24], ChannelCount)
+	binary.LittleEndian.PutUint32(h[24:28], SampleRate)
+	binary.LittleEndian.PutUint32(h[28:32], SampleRate*ChannelCount*bytesPerSample)
+	binary.LittleEndian.PutUint16(h[32:34], ChannelCount*bytesPerSample)
+	binary.LittleEndian.PutUint16(h[34:36], bytesPerSample*8)
+	copy(h[36:40], "data")
+	binary.LittleEndian.PutUint32(h[40:44], uint32(size))
+
+	if _, err := ww.w.Write(h); err != nil {
+		return fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package chirp
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/hiway/chirp/pkg/config"
+	"github.com/hiway/chirp/pkg/player"
+	"github.com/hiway/chirp/pkg/player/otoplayer"
+)
+
+// openPlayer tries each configured audio backend in order and returns the
+// first that opens. Failures are logged as warnings;
+// if every backend fails, sounds are discarded so the shell still starts.
+func openPlayer(cfg *config.Config, log zerolog.Logger) player.Player {
+	for _, name := range cfg.Backends() {
+		p, err := openBackend(name, cfg, log)
+		if err != nil {
+			log.Warn().Err(err).Str("backend", name).Msg("Audio backend unavailable, trying next")
+			continue
+		}
+		log.Info().Str("backend", name).Msg("Using audio backend")
+		return p
+	}
+	log.Warn().Msg("No audio backend available, sounds will be discarded")
+	return player.NewNullPlayer(log)
+}
+
+// openBackend creates the player for a single backend.
+func openBackend(name string, cfg *config.Config, log zerolog.Logger) (player.Player, error) {
+	switch name {
+	case config.BackendOto:
+		return otoplayer.New(log)
+	case config.BackendWAV:
+		if cfg.AudioOut == "" {
+			return nil, fmt.Errorf("no audio_out file set")
+		}
+		return player.NewWavPlayer(cfg.AudioOut, log)
+	case config.BackendNull:
+		return player.NewNullPlayer(log), nil
+	default:
+		return nil, fmt.Errorf("unknown audio backend '%s'", name)
+	}
+}
+
+// CheckBackend reports whether the named backend is usable. The sound card
+// is opened and closed again; the WAV output file is not touched.
+func CheckBackend(name string, cfg *config.Config, log zerolog.Logger) error {
+	switch name {
+	case config.BackendOto:
+		p, err := otoplayer.New(log)
+		if err != nil {
+			return err
+		}
+		return p.Close()
+	case config.BackendWAV:
+		if cfg.AudioOut == "" {
+			return fmt.Errorf("no audio_out file set")
+		}
+		return player.CheckWavOutput(cfg.AudioOut)
+	case config.BackendNull:
+		return nil
+	default:
+		return fmt.Errorf("unknown audio backend '%s'", name)
+	}
+}
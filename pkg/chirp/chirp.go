@@ -8,19 +8,29 @@ import (
 
 	"github.com/rs/zerolog"
 
+	"github.com/hiway/chirp/pkg/ansi"
 	"github.com/hiway/chirp/pkg/config"
+	"github.com/hiway/chirp/pkg/matcher"
 	"github.com/hiway/chirp/pkg/player"
 	"github.com/hiway/chirp/pkg/queue"
 	"github.com/hiway/chirp/pkg/sample"
 	"github.com/hiway/chirp/pkg/terminal"
 )
 
-// Chirp manages the terminal session with audio feedback.
+// Chirp manages the terminal session with audio feedback. The terminal is
+// created by Start; Pipe runs without one.
 type Chirp struct {
 	cfg      *config.Config
 	term     *terminal.Terminal
 	player   player.Player
 	queues   map[string]*queue.Queue
+	patterns *matcher.Automaton
+	inScan   *matcher.Scanner
+	outScan  *matcher.Scanner
+	inParse  *ansi.Parser
+	outParse *ansi.Parser
+	outStyle *matcher.StyleWatcher
+	outMu    sync.Mutex // Serializes output handling with the final flush
 	log      zerolog.Logger
 	stopOnce sync.Once
 	stopChan chan struct{}
@@ -29,35 +39,39 @@ type Chirp struct {
 // DefaultConfig returns a basic configuration for testing.
 func DefaultConfig() *config.Config {
 	cfg := &config.Config{
+		Tuning: sample.DefaultTuning,
 		Samples: map[string]*sample.SampleConfig{
 			"local": {
-				Duration:  50,
-				Frequency: 392, // G4
-				Volume:    0.3,
+				Duration: 50,
+				Note:     "G4",
+				Volume:   0.3,
 			},
 			"remote": {
-				Duration:  50,
-				Frequency: 587, // D5
-				Volume:    0.3,
+				Duration: 50,
+				Note:     "D5",
+				Volume:   0.3,
 			},
 		},
 		Queues: map[string]*config.Queue{
 			"local": {
 				Match:      []string{"\r", "\n"},
+				Direction:  config.DirectionInput,
 				SampleName: "local",
 				MaxLength:  1,
 			},
 			"remote": {
 				Match:      []string{"$", "#", "%"},
+				Direction:  config.DirectionOutput,
 				SampleName: "remote",
 				MaxLength:  1,
 			},
 		},
 	}
 
-	// Set names and link samples
+	// Set names, resolve notes and link samples
 	for name, s := range cfg.Samples {
 		s.Name = name
+		s.ResolvePitch(cfg.Tuning)
 	}
 	for name, q := range cfg.Queues {
 		q.Name = name
@@ -67,14 +81,38 @@ func DefaultConfig() *config.Config {
 	return cfg
 }
 
+// Command returns the command chirp wraps: the configured command if any,
+// else the configured shell, $SHELL, or /bin/sh.
+func Command(cfg *config.Config) []string {
+	if len(cfg.Command) > 0 {
+		return cfg.Command
+	}
+	if cfg.Shell != "" {
+		return []string{cfg.Shell}
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return []string{shell}
+	}
+	return []string{"/bin/sh"} // Fallback if SHELL is not set
+}
+
 // New creates a new Chirp instance with the given configuration.
 func New(cfg *config.Config, log zerolog.Logger) (*Chirp, error) {
 	log = log.With().Str("component", "chirp").Logger()
 
-	// Create audio player
-	p, err := player.NewOtoPlayer(log)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create audio player: %w", err)
+	// Create audio player, falling back through the configured backends
+	return newChirp(cfg, openPlayer(cfg, log), log)
+}
+
+// newChirp creates a Chirp instance that plays its sounds on p.
+func newChirp(cfg *config.Config, p player.Player, log zerolog.Logger) (*Chirp, error) {
+	if d, ok := p.(player.Ducker); ok && cfg.DuckLevel != nil {
+		d.SetDuckLevel(*cfg.DuckLevel)
+	}
+
+	// Render samples up front so the first keypress plays without delay
+	if pr, ok := p.(player.Prerenderer); ok {
+		pr.Prerender(cfg.Samples)
 	}
 
 	// Create queues
@@ -86,40 +124,43 @@ func New(cfg *config.Config, log zerolog.Logger) (*Chirp, error) {
 			for _, q := range queues {
 				q.Stop()
 			}
+			p.Close()
 			return nil, fmt.Errorf("failed to create queue '%s': %w", name, err)
 		}
 		queues[name] = q
 	}
 
-	shell := os.Getenv("SHELL")
-	if shell == "" {
-		shell = "/bin/sh" // Fallback if SHELL is not set
-	}
-
-	// Create terminal
-	term := terminal.NewTerminal(shell, log, os.Stdin, os.Stdout)
+	// Compile all queue patterns into a single automaton
+	patterns := matcher.Compile(cfg.Queues)
 
 	c := &Chirp{
 		cfg:      cfg,
-		term:     term,
 		player:   p,
 		queues:   queues,
+		patterns: patterns,
+		inScan:   patterns.NewScanner(config.Input),
+		outScan:  patterns.NewScanner(config.Output),
+		inParse:  ansi.NewParser(),
+		outParse: ansi.NewParser(),
+		outStyle: matcher.NewStyleWatcher(cfg.Queues),
 		log:      log,
 		stopChan: make(chan struct{}),
 	}
 
-	// Set up terminal handlers
-	term.HandleInput = c.handleInput
-	term.HandleOutput = c.handleOutput
-
 	return c, nil
 }
 
-// Start begins the terminal session with audio feedback.
-func (c *Chirp) Start(ctx context.Context) error {
+// Start begins the terminal session with audio feedback. It blocks until
+// the wrapped command exits and returns its exit status.
+func (c *Chirp) Start(ctx context.Context) (terminal.ExitStatus, error) {
+	// Create the terminal and set up its handlers
+	c.term = terminal.NewTerminal(Command(c.cfg), c.log, os.Stdin, os.Stdout)
+	c.term.HandleInput = c.handleInput
+	c.term.HandleOutput = c.handleOutput
+
 	// Start the terminal
 	if err := c.term.Start(); err != nil {
-		return fmt.Errorf("failed to start terminal: %w", err)
+		return terminal.ExitStatus{}, fmt.Errorf("failed to start terminal: %w", err)
 	}
 
 	c.log.Info().Msg("Chirp started successfully")
@@ -135,12 +176,17 @@ func (c *Chirp) Start(ctx context.Context) error {
 		}
 	}()
 
-	// Wait for terminal to exit
-	if err := c.term.Wait(); err != nil {
-		return fmt.Errorf("terminal exited with error: %w", err)
+	// Wait for the wrapped command to exit, let the sounds for its last
+	// output play, then shut down
+	status, err := c.term.Wait()
+	c.flushOutput()
+	c.drain(ctx)
+	c.Stop()
+	if err != nil {
+		return terminal.ExitStatus{}, fmt.Errorf("terminal exited with error: %w", err)
 	}
 
-	return nil
+	return status, nil
 }
 
 // Stop gracefully shuts down the terminal session and audio.
@@ -155,8 +201,10 @@ func (c *Chirp) Stop() {
 			q.Stop()
 		}
 
-		// Stop terminal
-		c.term.Stop()
+		// Stop terminal, if one was started
+		if c.term != nil {
+			c.term.Stop()
+		}
 
 		// Close audio player
 		if err := c.player.Close(); err != nil {
@@ -167,34 +215,79 @@ func (c *Chirp) Stop() {
 	})
 }
 
-// handleInput processes terminal input and triggers sounds.
+// handleInput processes terminal input and triggers sounds. The escape
+// sequences sent by keys such as the arrows, Home or F1 are dropped, so
+// moving the cursor does not sound like typing. A key's sequence arrives
+// in a single read, so an ESC left unfinished at the end of a read is the
+// Escape key on its own; it is discarded rather than joined to the next
+// key.
 func (c *Chirp) handleInput(data []byte) error {
-	for _, b := range data {
-		for name, q := range c.queues {
-			if q.Config.MatchesInput(b) {
+	c.inParse.Feed(data, func(text []byte) {
+		c.inScan.Feed(text, func(m matcher.Match) {
+			for _, name := range m.Queues {
 				c.log.Trace().
 					Str("queue", name).
-					Str("char", string(b)).
+					Str("pattern", m.Pattern).
 					Msg("Input matched queue pattern")
-				q.Add(string(b))
+				c.queues[name].Add(m.Pattern)
 			}
-		}
-	}
+		})
+	}, func(seq ansi.Sequence) {
+		c.log.Trace().Stringer("sequence", seq).Msg("Ignoring input escape sequence")
+	})
+	c.inParse.Reset()
 	return nil
 }
 
-// handleOutput processes terminal output and triggers sounds.
+// handleOutput processes terminal output and triggers sounds. Escape
+// sequences are parsed out first, so only printable text reaches the
+// pattern scanner and the sequences themselves can be matched on purpose.
+// SGR sequences also set the style that text is checked against.
 func (c *Chirp) handleOutput(data []byte) error {
-	for _, b := range data {
-		for name, q := range c.queues {
-			if q.Config.MatchesOutput(b) {
-				c.log.Trace().
-					Str("queue", name).
-					Str("char", string(b)).
-					Msg("Output matched queue pattern")
-				q.Add(string(b))
-			}
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	c.outParse.Feed(data, func(text []byte) {
+		c.outStyle.Text(text, c.outputMatched)
+		c.outScan.Feed(text, c.outputMatched)
+	}, func(seq ansi.Sequence) {
+		c.outStyle.Sequence(seq)
+		c.patterns.MatchSequence(seq, c.outputMatched)
+	})
+	return nil
+}
+
+// flushOutput matches what the output scanner still holds back, such as
+// a last character or a whole word, at the end of the output.
+func (c *Chirp) flushOutput() {
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	c.outScan.Flush(c.outputMatched)
+}
+
+// drain waits until every queue has played its waiting sounds, or until
+// ctx is canceled or chirp is stopped.
+func (c *Chirp) drain(ctx context.Context) {
+	drained := make(chan struct{})
+	go func() {
+		for _, q := range c.queues {
+			q.Drain()
 		}
+		close(drained)
+	}()
+	select {
+	case <-ctx.Done():
+		c.log.Info().Msg("Context canceled, stopping chirp")
+	case <-drained:
+	}
+}
+
+// outputMatched queues a match found in terminal output.
+func (c *Chirp) outputMatched(m matcher.Match) {
+	for _, name := range m.Queues {
+		c.log.Trace().
+			Str("queue", name).
+			Str("pattern", m.Pattern).
+			Msg("Output matched queue pattern")
+		c.queues[name].Add(m.Pattern)
 	}
-	return nil
 }
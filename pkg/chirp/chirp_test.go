@@ -0,0 +1,88 @@
+package chirp
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/hiway/chirp/pkg/config"
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+// countingPlayer counts the sounds played.
+type countingPlayer struct {
+	plays atomic.Int32
+}
+
+func (p *countingPlayer) Play(*sample.SampleConfig, int) error {
+	p.plays.Add(1)
+	return nil
+}
+
+func (p *countingPlayer) Close() error {
+	return nil
+}
+
+// newTypingChirp returns a Chirp with a character map on typed input.
+func newTypingChirp(t *testing.T) (*Chirp, *countingPlayer) {
+	t.Helper()
+	key := &sample.SampleConfig{Name: "key", Frequency: 440, Duration: 50, Volume: 0.3}
+	if err := key.Validate(); err != nil {
+		t.Fatalf("sample Validate() = %v", err)
+	}
+	q := &config.Queue{
+		Name:       "typing",
+		Direction:  config.DirectionInput,
+		SampleName: "key",
+		Sample:     key,
+		MaxLength:  100,
+		Map: []config.MapRule{
+			{Class: config.ClassLetter},
+			{Class: config.ClassPunct},
+		},
+	}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("queue Validate() = %v", err)
+	}
+	cfg := &config.Config{
+		Samples: map[string]*sample.SampleConfig{"key": key},
+		Queues:  map[string]*config.Queue{"typing": q},
+	}
+
+	p := &countingPlayer{}
+	c, err := newChirp(cfg, p, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("newChirp() = %v", err)
+	}
+	t.Cleanup(c.Stop)
+	return c, p
+}
+
+func TestInputEscapeSequences(t *testing.T) {
+	tests := []struct {
+		name  string
+		reads []string
+		want  int32
+	}{
+		{"up", []string{"\x1b[A"}, 0},
+		{"up in application mode", []string{"\x1bOA"}, 0},
+		{"ctrl right", []string{"\x1b[1;5C"}, 0},
+		{"home", []string{"\x1b[H"}, 0},
+		{"f1", []string{"\x1bOP"}, 0},
+		{"f5", []string{"\x1b[15~"}, 0},
+		{"letters around a key", []string{"a\x1b[Db"}, 2},
+		{"escape key then a letter", []string{"\x1b", "j"}, 1},
+		{"punctuation", []string{"[;"}, 2},
+	}
+	for _, tt := range tests {
+		c, p := newTypingChirp(t)
+		for _, r := range tt.reads {
+			c.handleInput([]byte(r))
+		}
+		c.queues["typing"].Drain()
+		if got := p.plays.Load(); got != tt.want {
+			t.Errorf("%s: plays = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
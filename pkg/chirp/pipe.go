@@ -0,0 +1,60 @@
+package chirp
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Pipe copies in to out unchanged while running the output queues over it,
+// as a filter such as `tail -f app.log | chirp pipe`. No PTY is used and
+// the terminal mode is left alone. At the end of input Pipe waits for the
+// queued sounds to play, then stops chirp.
+func (c *Chirp) Pipe(ctx context.Context, in io.Reader, out io.Writer) error {
+	defer c.Stop()
+	c.log.Info().Msg("Chirp pipe started")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.copyPipe(in, out)
+	}()
+
+	select {
+	case <-ctx.Done():
+		c.log.Info().Msg("Context canceled, stopping chirp")
+		return nil
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	}
+
+	// Let the sounds for the last matches finish
+	c.drain(ctx)
+	return nil
+}
+
+// copyPipe copies in to out through the output handler until in ends.
+func (c *Chirp) copyPipe(in io.Reader, out io.Writer) error {
+	buf := make([]byte, 8192)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			data := buf[:n]
+			if hErr := c.handleOutput(data); hErr != nil {
+				c.log.Error().Err(hErr).Msg("Output handler failed")
+			}
+			if _, wErr := out.Write(data); wErr != nil {
+				return fmt.Errorf("failed to write output: %w", wErr)
+			}
+		}
+		if err == io.EOF {
+			c.log.Debug().Msg("End of input")
+			c.flushOutput()
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+	}
+}
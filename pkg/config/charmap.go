@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+// Character classes accepted by the map class setting.
+const (
+	ClassLetter = "letter"
+	ClassDigit  = "digit"
+	ClassPunct  = "punct"
+	ClassSymbol = "symbol"
+	ClassSpace  = "space"
+	ClassAny    = "any"
+)
+
+// Scales are the named scales accepted by the map scale setting, as
+// semitones above the root within one octave.
+var Scales = map[string][]float64{
+	"chromatic":        {0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	"major":            {0, 2, 4, 5, 7, 9, 11},
+	"minor":            {0, 2, 3, 5, 7, 8, 10},
+	"pentatonic":       {0, 2, 4, 7, 9},
+	"minor_pentatonic": {0, 3, 5, 7, 10},
+	"whole_tone":       {0, 2, 4, 6, 8, 10},
+}
+
+// MapRule maps typed or printed graphemes onto the pitch or timbre of the
+// sound played for them. A queue's rules are tried in order and the first
+// that covers a grapheme decides its sound.
+type MapRule struct {
+	Class      string    `toml:"class"`   // Character class covered: letter, digit, punct, symbol, space or any
+	Chars      string    `toml:"chars"`   // Grapheme clusters covered instead of a class, in pitch order
+	Scale      string    `toml:"scale"`   // Named scale the graphemes are spread over
+	Steps      []float64 `toml:"steps"`   // Semitones above the root within an octave, instead of a named scale
+	Root       string    `toml:"root"`    // Note of the first step, e.g. "C4" (default: the sample's own pitch)
+	Octaves    int       `toml:"octaves"` // Octaves the steps repeat over before wrapping (default 1)
+	SampleName string    `toml:"sample"`  // Sample to play instead of the queue's
+
+	Sample   *sample.SampleConfig `toml:"-"` // Linked after config load
+	RootFreq float64              `toml:"-"` // Resolved from Root, 0 if unset
+	chars    []string             // Graphemes split from Chars
+}
+
+// Validate checks the rule and resolves its scale.
+func (r *MapRule) Validate() error {
+	switch {
+	case r.Class != "" && r.Chars != "":
+		return fmt.Errorf("class and chars cannot both be set")
+	case r.Chars != "":
+		r.chars = r.chars[:0]
+		rest, state := r.Chars, -1
+		for len(rest) > 0 {
+			var c string
+			c, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+			r.chars = append(r.chars, c)
+		}
+	case r.Class == "":
+		return fmt.Errorf("class or chars must be set")
+	}
+	switch r.Class {
+	case "", ClassLetter, ClassDigit, ClassPunct, ClassSymbol, ClassSpace, ClassAny:
+	default:
+		return fmt.Errorf("class must be %q, %q, %q, %q, %q or %q, got %q",
+			ClassLetter, ClassDigit, ClassPunct, ClassSymbol, ClassSpace, ClassAny, r.Class)
+	}
+
+	if r.Scale != "" {
+		if len(r.Steps) > 0 {
+			return fmt.Errorf("scale and steps cannot both be set")
+		}
+		steps, ok := Scales[r.Scale]
+		if !ok {
+			return fmt.Errorf("unknown scale %q", r.Scale)
+		}
+		r.Steps = steps
+	}
+	if r.Octaves < 0 {
+		return fmt.Errorf("octaves cannot be negative")
+	}
+	if r.Octaves == 0 {
+		r.Octaves = 1
+	}
+	if r.Root != "" && len(r.Steps) == 0 {
+		return fmt.Errorf("root needs a scale or steps")
+	}
+	return nil
+}
+
+// resolve links the rule's sample and resolves its root note.
+func (r *MapRule) resolve(samples map[string]*sample.SampleConfig, tuning float64) error {
+	if r.SampleName != "" {
+		s, ok := samples[r.SampleName]
+		if !ok {
+			return fmt.Errorf("unknown sample '%s'", r.SampleName)
+		}
+		r.Sample = s
+	}
+	if r.Root != "" {
+		freq, err := sample.NoteFrequency(r.Root, tuning)
+		if err != nil {
+			return err
+		}
+		r.RootFreq = freq
+	}
+	return nil
+}
+
+// index returns the position of a grapheme among those the rule covers,
+// or false if the rule does not cover it. ASCII letters count from 'a'
+// regardless of case and digits from '0'; other graphemes are ordered by
+// code point.
+func (r *MapRule) index(text string) (int, bool) {
+	if len(r.chars) > 0 {
+		for i, c := range r.chars {
+			if c == text {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	c, _ := utf8.DecodeRuneInString(text)
+	var covered bool
+	switch r.Class {
+	case ClassLetter:
+		covered = unicode.IsLetter(c)
+	case ClassDigit:
+		covered = unicode.IsDigit(c)
+	case ClassPunct:
+		covered = unicode.IsPunct(c)
+	case ClassSymbol:
+		covered = unicode.IsSymbol(c)
+	case ClassSpace:
+		covered = unicode.IsSpace(c)
+	case ClassAny:
+		covered = c != utf8.RuneError
+	}
+	if !covered {
+		return 0, false
+	}
+	switch {
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A'), true
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	}
+	return int(c), true
+}
+
+// Semitones returns the pitch of the grapheme at the given index, in
+// semitones above the root. The steps repeat each octave and wrap after
+// Octaves octaves.
+func (r *MapRule) Semitones(index int) float64 {
+	if len(r.Steps) == 0 {
+		return 0
+	}
+	index %= len(r.Steps) * r.Octaves
+	return float64(index/len(r.Steps))*12 + r.Steps[index%len(r.Steps)]
+}
+
+// Lookup returns the first rule of the queue's map that covers a grapheme,
+// and the grapheme's position within it.
+func (q *Queue) Lookup(text string) (*MapRule, int, bool) {
+	for i := range q.Map {
+		if idx, ok := q.Map[i].index(text); ok {
+			return &q.Map[i], idx, true
+		}
+	}
+	return nil, 0, false
+}
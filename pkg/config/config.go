@@ -3,27 +3,152 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
 
 	"github.com/BurntSushi/toml"
 	"github.com/rs/zerolog"
 
+	"github.com/hiway/chirp/pkg/ansi"
 	"github.com/hiway/chirp/pkg/sample"
 )
 
+// Stream identifies one direction of the terminal session.
+type Stream int
+
+const (
+	// Input is the stream typed by the user
+	Input Stream = iota
+	// Output is the stream printed by the wrapped program
+	Output
+)
+
+// Values accepted by the queue direction setting.
+const (
+	DirectionInput  = "input"
+	DirectionOutput = "output"
+	DirectionBoth   = "both"
+)
+
+// Values accepted by the queue overflow setting.
+const (
+	OverflowDropNewest    = "drop_newest"    // Discard the new item
+	OverflowDropOldest    = "drop_oldest"    // Discard the oldest waiting item
+	OverflowReplaceLatest = "replace_latest" // Replace the newest waiting item
+	OverflowCoalesce      = "coalesce"       // Merge into the newest waiting item and count it
+)
+
+// Audio backends accepted by the audio_backend setting.
+const (
+	BackendOto  = "oto"  // Play through the sound card
+	BackendWAV  = "wav"  // Write to the audio_out WAV file
+	BackendNull = "null" // Discard all sounds
+)
+
 // Queue defines the configuration for a sound queue.
 type Queue struct {
-	Name       string               `toml:"-"`      // Name is derived from map key
-	Match      []string             `toml:"match"`  // Patterns to match
-	SampleName string               `toml:"sample"` // Name of the sample to play
-	MaxLength  int                  `toml:"max_length"`
-	Sample     *sample.SampleConfig `toml:"-"` // Linked after config load
+	Name         string               `toml:"-"`              // Name is derived from map key
+	Match        []string             `toml:"match"`          // Patterns to match
+	Regex        []string             `toml:"regex"`          // Regular expressions to match
+	Direction    string               `toml:"direction"`      // Streams Match and Regex apply to: input, output or both
+	InputMatch   []string             `toml:"input_match"`    // Patterns matched only against input
+	OutputMatch  []string             `toml:"output_match"`   // Patterns matched only against output
+	CaseSense    *bool                `toml:"case_sensitive"` // Match literal patterns case-sensitively (default true)
+	WholeWord    bool                 `toml:"whole_word"`     // Only match literal patterns at word boundaries
+	Escape       []string             `toml:"escape"`         // Escape sequences to match in output, e.g. "csi:J" or "osc:0"
+	SGR          []string             `toml:"sgr"`            // Output text styles to match, e.g. "fg:red" or "bold+fg:yellow"
+	SampleName   string               `toml:"sample"`         // Name of the sample to play
+	MaxLength    int                  `toml:"max_length"`
+	Overflow     string               `toml:"overflow"`       // What to do when the queue is full (default drop_newest)
+	CooldownMs   int                  `toml:"cooldown_ms"`    // Ignore matches for this long after one is accepted
+	MaxPerSecond int                  `toml:"max_per_second"` // Accept at most this many matches in any second
+	DebounceMs   int                  `toml:"debounce_ms"`    // Play once matches have paused for this long
+	Priority     int                  `toml:"priority"`       // Sounds of lower priority are ducked while this queue plays
+	Escalate     *Escalation          `toml:"escalate"`       // Raise the pitch of repeated matches
+	Map          []MapRule            `toml:"map"`            // Play each grapheme at a pitch or timbre of its own
+	Sample       *sample.SampleConfig `toml:"-"`              // Linked after config load
+	Regexps      []*regexp.Regexp     `toml:"-"`              // Compiled from Regex during validation
+	EscapeSpecs  []ansi.Spec          `toml:"-"`              // Parsed from Escape during validation
+	StyleSpecs   []ansi.StyleSpec     `toml:"-"`              // Parsed from SGR during validation
+}
+
+// Default escalation limits.
+const (
+	DefaultEscalateSteps   = 6
+	DefaultEscalateResetMs = 2000
+)
+
+// Escalation raises the pitch of each repeated match within a burst.
+type Escalation struct {
+	Semitones float64 `toml:"semitones"` // Pitch rise per repeat; negative values fall
+	MaxSteps  int     `toml:"max_steps"` // Highest number of rises (default 6)
+	ResetMs   int     `toml:"reset_ms"`  // Quiet time that ends a burst (default 2000)
+}
+
+// Validate checks the escalation and fills in defaults.
+func (e *Escalation) Validate() error {
+	if e.Semitones == 0 {
+		return fmt.Errorf("semitones cannot be zero")
+	}
+	if e.MaxSteps < 0 {
+		return fmt.Errorf("max_steps cannot be negative")
+	}
+	if e.MaxSteps == 0 {
+		e.MaxSteps = DefaultEscalateSteps
+	}
+	if e.ResetMs < 0 {
+		return fmt.Errorf("reset_ms cannot be negative")
+	}
+	if e.ResetMs == 0 {
+		e.ResetMs = DefaultEscalateResetMs
+	}
+	return nil
 }
 
 // Validate checks if the queue configuration is valid.
 func (q *Queue) Validate() error {
-	if len(q.Match) == 0 {
+	if len(q.Match) == 0 && len(q.Regex) == 0 && len(q.InputMatch) == 0 && len(q.OutputMatch) == 0 &&
+		len(q.Escape) == 0 && len(q.SGR) == 0 && len(q.Map) == 0 {
 		return fmt.Errorf("match patterns cannot be empty")
 	}
+	// A character map hears every grapheme, so other patterns would
+	// queue the same text twice
+	if len(q.Map) > 0 && (len(q.Match) > 0 || len(q.Regex) > 0 || len(q.InputMatch) > 0 ||
+		len(q.OutputMatch) > 0 || len(q.Escape) > 0 || len(q.SGR) > 0) {
+		return fmt.Errorf("map cannot be combined with other patterns; use a separate queue")
+	}
+	switch q.Direction {
+	case "":
+		q.Direction = DirectionBoth // Default to both streams if not specified
+	case DirectionInput, DirectionOutput, DirectionBoth:
+	default:
+		return fmt.Errorf("direction must be %q, %q or %q, got %q",
+			DirectionInput, DirectionOutput, DirectionBoth, q.Direction)
+	}
+	q.Regexps = q.Regexps[:0]
+	for _, expr := range q.Regex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %w", expr, err)
+		}
+		q.Regexps = append(q.Regexps, re)
+	}
+	q.EscapeSpecs = q.EscapeSpecs[:0]
+	for _, spec := range q.Escape {
+		sp, err := ansi.ParseSpec(spec)
+		if err != nil {
+			return fmt.Errorf("invalid escape %q: %w", spec, err)
+		}
+		q.EscapeSpecs = append(q.EscapeSpecs, sp)
+	}
+	q.StyleSpecs = q.StyleSpecs[:0]
+	for _, spec := range q.SGR {
+		sp, err := ansi.ParseStyleSpec(spec)
+		if err != nil {
+			return fmt.Errorf("invalid sgr %q: %w", spec, err)
+		}
+		q.StyleSpecs = append(q.StyleSpecs, sp)
+	}
 	if q.SampleName == "" {
 		return fmt.Errorf("sample name cannot be empty")
 	}
@@ -33,31 +158,90 @@ func (q *Queue) Validate() error {
 	if q.MaxLength == 0 {
 		q.MaxLength = 1 // Default to 1 if not specified
 	}
+	if q.CooldownMs < 0 {
+		return fmt.Errorf("cooldown_ms cannot be negative")
+	}
+	if q.MaxPerSecond < 0 {
+		return fmt.Errorf("max_per_second cannot be negative")
+	}
+	if q.DebounceMs < 0 {
+		return fmt.Errorf("debounce_ms cannot be negative")
+	}
+	for i := range q.Map {
+		if err := q.Map[i].Validate(); err != nil {
+			return fmt.Errorf("invalid map rule %d: %w", i+1, err)
+		}
+	}
+	if q.Escalate != nil {
+		if err := q.Escalate.Validate(); err != nil {
+			return fmt.Errorf("invalid escalate: %w", err)
+		}
+	}
+	switch q.Overflow {
+	case "":
+		q.Overflow = OverflowDropNewest
+	case OverflowDropNewest, OverflowDropOldest, OverflowReplaceLatest, OverflowCoalesce:
+	default:
+		return fmt.Errorf("overflow must be %q, %q, %q or %q, got %q",
+			OverflowDropNewest, OverflowDropOldest, OverflowReplaceLatest, OverflowCoalesce, q.Overflow)
+	}
 	return nil
 }
 
-// MatchesInput checks if a byte matches any input pattern.
-func (q *Queue) MatchesInput(b byte) bool {
-	// TODO: Implement more sophisticated pattern matching
-	s := string(b)
-	for _, pattern := range q.Match {
-		if pattern == s {
-			return true
-		}
+// Applies reports whether Match and Regex apply to the given stream.
+func (q *Queue) Applies(s Stream) bool {
+	switch q.Direction {
+	case DirectionInput:
+		return s == Input
+	case DirectionOutput:
+		return s == Output
+	default:
+		return true
 	}
-	return false
 }
 
-// MatchesOutput checks if a byte matches any output pattern.
-func (q *Queue) MatchesOutput(b byte) bool {
-	// For now, using the same logic as input matching
-	return q.MatchesInput(b)
+// CaseSensitive reports whether literal patterns must match case exactly.
+func (q *Queue) CaseSensitive() bool {
+	return q.CaseSense == nil || *q.CaseSense
+}
+
+// Patterns returns the literal patterns matched against the given stream.
+func (q *Queue) Patterns(s Stream) []string {
+	var patterns []string
+	if q.Applies(s) {
+		patterns = append(patterns, q.Match...)
+	}
+	if s == Input {
+		patterns = append(patterns, q.InputMatch...)
+	} else {
+		patterns = append(patterns, q.OutputMatch...)
+	}
+	return patterns
 }
 
 // Config holds the complete chirp configuration.
 type Config struct {
-	Samples map[string]*sample.SampleConfig `toml:"samples"`
-	Queues  map[string]*Queue               `toml:"queues"`
+	Tuning       float64                         `toml:"tuning"`        // Frequency of A4 in Hz for note names (default 440)
+	AudioBackend []string                        `toml:"audio_backend"` // Audio backends to try in order
+	AudioOut     string                          `toml:"audio_out"`     // WAV file written by the wav backend
+	DuckLevel    *float64                        `toml:"duck_level"`    // Gain of lower-priority sounds, 0 cuts them (default 0.25)
+	Shell        string                          `toml:"shell"`         // Shell to wrap (default $SHELL)
+	Command      []string                        `toml:"-"`             // Command to wrap instead of the shell
+	Samples      map[string]*sample.SampleConfig `toml:"samples"`
+	Queues       map[string]*Queue               `toml:"queues"`
+}
+
+// Backends returns the audio backends to try in order. Without an
+// audio_backend setting, the wav backend is used if audio_out is set and
+// the sound card otherwise, falling back to discarding sounds.
+func (c *Config) Backends() []string {
+	if len(c.AudioBackend) > 0 {
+		return c.AudioBackend
+	}
+	if c.AudioOut != "" {
+		return []string{BackendWAV, BackendNull}
+	}
+	return []string{BackendOto, BackendNull}
 }
 
 // LoadConfig reads and validates configuration from a TOML file.
@@ -74,13 +258,41 @@ func LoadConfig(path string, log zerolog.Logger) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse TOML: %w", err)
 	}
 
-	// Set names from map keys and validate
+	if cfg.Tuning == 0 {
+		cfg.Tuning = sample.DefaultTuning
+	}
+	if cfg.Tuning < 0 {
+		return nil, fmt.Errorf("tuning must be positive, got %f", cfg.Tuning)
+	}
+	if cfg.DuckLevel != nil && (*cfg.DuckLevel < 0 || *cfg.DuckLevel > 1) {
+		return nil, fmt.Errorf("duck_level must be between 0.0 and 1.0, got %f", *cfg.DuckLevel)
+	}
+	for _, b := range cfg.AudioBackend {
+		switch b {
+		case BackendOto, BackendWAV, BackendNull:
+		default:
+			return nil, fmt.Errorf("audio_backend must contain only '%s', '%s' or '%s', got '%s'",
+				BackendOto, BackendWAV, BackendNull, b)
+		}
+	}
+	// Like sample files, the audio output path is relative to the config file
+	if cfg.AudioOut != "" && !filepath.IsAbs(cfg.AudioOut) {
+		cfg.AudioOut = filepath.Join(filepath.Dir(path), cfg.AudioOut)
+	}
+
+	// Set names from map keys, resolve note names and validate
 	for name, s := range cfg.Samples {
 		s.Name = name
+		if err := s.ResolvePitch(cfg.Tuning); err != nil {
+			return nil, fmt.Errorf("invalid sample '%s': %w", name, err)
+		}
 		if err := s.Validate(); err != nil {
 			return nil, fmt.Errorf("invalid sample '%s': %w", name, err)
 		}
-		log.Debug().Str("sample", name).Msg("Validated sample")
+		if err := s.LoadFile(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("invalid sample '%s': %w", name, err)
+		}
+		log.Debug().Str("sample", name).Float64("frequency_hz", s.Frequency).Msg("Validated sample")
 	}
 
 	for name, queue := range cfg.Queues {
@@ -95,6 +307,11 @@ func LoadConfig(path string, log zerolog.Logger) (*Config, error) {
 			return nil, fmt.Errorf("queue '%s' references unknown sample '%s'", name, queue.SampleName)
 		}
 		queue.Sample = s
+		for i := range queue.Map {
+			if err := queue.Map[i].resolve(cfg.Samples, cfg.Tuning); err != nil {
+				return nil, fmt.Errorf("queue '%s' map rule %d: %w", name, i+1, err)
+			}
+		}
 
 		log.Debug().
 			Str("queue", name).
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+func TestQueueValidateMap(t *testing.T) {
+	letters := []MapRule{{Class: ClassLetter}}
+	tests := []struct {
+		name    string
+		queue   Queue
+		wantErr bool
+	}{
+		{"map alone", Queue{Map: letters}, false},
+		{"map and match", Queue{Map: letters, Match: []string{"error"}}, true},
+		{"map and regex", Queue{Map: letters, Regex: []string{"e.*"}}, true},
+		{"map and input_match", Queue{Map: letters, InputMatch: []string{"x"}}, true},
+		{"map and output_match", Queue{Map: letters, OutputMatch: []string{"x"}}, true},
+		{"map and escape", Queue{Map: letters, Escape: []string{"csi:J"}}, true},
+		{"map and sgr", Queue{Map: letters, SGR: []string{"fg:red"}}, true},
+	}
+	for _, tt := range tests {
+		q := tt.queue
+		q.Name = "typing"
+		q.SampleName = "key"
+		q.Sample = &sample.SampleConfig{Name: "key", Frequency: 440}
+		q.MaxLength = 1
+		if err := q.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package matcher
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/cases"
+)
+
+// zeroWidthJoiner glues emoji into a single cluster across reads.
+const zeroWidthJoiner = '‍'
+
+// Segmenter splits a UTF-8 byte stream into grapheme clusters. Bytes that
+// may still be extended by the next read, such as a partial UTF-8 sequence
+// or an emoji ending in a zero-width joiner, are held back until more data
+// arrives. A Segmenter is not safe for concurrent use.
+//
+// With holdLast set, the last cluster of every read is held back, since a
+// combining mark or emoji modifier in the next read may still extend it.
+// Control characters are never extended and are not held.
+type Segmenter struct {
+	pending  []byte
+	state    int
+	holdLast bool
+}
+
+// NewSegmenter creates a segmenter positioned at the start of a stream.
+// Set holdLast for program output, where a cluster can be split across
+// reads; typed input is not held so keystrokes are not delayed.
+func NewSegmenter(holdLast bool) *Segmenter {
+	return &Segmenter{state: -1, holdLast: holdLast}
+}
+
+// Feed splits the pending bytes plus data into grapheme clusters and calls
+// fn for each complete one. The cluster slice is only valid during the call.
+//
+// Control characters always form their own cluster, so "\r\n" yields "\r"
+// and "\n" rather than the single cluster Unicode defines for it.
+func (g *Segmenter) Feed(data []byte, fn func(cluster []byte)) {
+	buf := data
+	if len(g.pending) > 0 {
+		g.pending = append(g.pending, data...)
+		buf = g.pending
+	}
+
+	for len(buf) > 0 {
+		// Fast path: an ASCII byte followed by another ASCII byte, or any
+		// control character, is a cluster by itself.
+		if b := buf[0]; b < utf8.RuneSelf && (b < 0x20 || b == 0x7f || len(buf) > 1 && buf[1] < utf8.RuneSelf) {
+			fn(buf[:1])
+			buf = buf[1:]
+			g.state = -1
+			continue
+		}
+
+		cluster, rest, _, state := uniseg.Step(buf, g.state)
+		if len(rest) == 0 && (g.holdLast || incomplete(cluster)) {
+			break
+		}
+		fn(cluster)
+		buf = rest
+		g.state = state
+	}
+
+	// Hold back whatever could not be emitted yet
+	g.pending = append(g.pending[:0], buf...)
+}
+
+// Flush emits the held back bytes as final clusters, for the end of the
+// stream, where nothing more can extend them.
+func (g *Segmenter) Flush(fn func(cluster []byte)) {
+	buf := g.pending
+	for len(buf) > 0 {
+		cluster, rest, _, state := uniseg.Step(buf, g.state)
+		fn(cluster)
+		buf = rest
+		g.state = state
+	}
+	g.Reset()
+}
+
+// Reset discards any held back bytes.
+func (g *Segmenter) Reset() {
+	g.pending = g.pending[:0]
+	g.state = -1
+}
+
+// incomplete reports whether a cluster at the end of a read may continue
+// in the next one.
+func incomplete(cluster []byte) bool {
+	// A multi-byte sequence cut off by the read boundary
+	for i := len(cluster) - 1; i >= 0 && i >= len(cluster)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(cluster[i]) {
+			if !utf8.FullRune(cluster[i:]) {
+				return true
+			}
+			break
+		}
+	}
+
+	r, size := utf8.DecodeLastRune(cluster)
+	if r == zeroWidthJoiner {
+		return true
+	}
+	// A lone regional indicator is half of a flag
+	return r >= 0x1F1E6 && r <= 0x1F1FF && size == len(cluster)
+}
+
+// folder applies Unicode case folding to grapheme clusters.
+type folder struct {
+	caser cases.Caser
+	buf   []byte
+}
+
+func newFolder() *folder {
+	return &folder{caser: cases.Fold()}
+}
+
+// fold returns the case-folded form of b. The result is only valid until
+// the next call.
+func (f *folder) fold(b []byte) []byte {
+	ascii := true
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if !ascii {
+		return f.caser.Bytes(b)
+	}
+
+	f.buf = append(f.buf[:0], b...)
+	for i, c := range f.buf {
+		if 'A' <= c && c <= 'Z' {
+			f.buf[i] = c + 'a' - 'A'
+		}
+	}
+	return f.buf
+}
+
+// isWordRune reports whether r can be part of a word.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
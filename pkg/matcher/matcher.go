@@ -0,0 +1,455 @@
+package matcher
+
+import (
+	"regexp"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/hiway/chirp/pkg/ansi"
+	"github.com/hiway/chirp/pkg/config"
+)
+
+// RegexWindow is the number of trailing stream bytes kept for regular
+// expression matching. A regex match longer than this may be missed.
+const RegexWindow = 4096
+
+// Match describes a pattern found in the stream.
+type Match struct {
+	Pattern string   // The pattern that matched, or the matched text for a regex or character map
+	Queues  []string // Names of the queues the pattern belongs to
+}
+
+// regexPattern is a compiled regular expression and its owning queues.
+type regexPattern struct {
+	re     *regexp.Regexp
+	queues [2][]string // Owning queues, by stream
+}
+
+// escapePattern is a queue's set of escape sequence specs.
+type escapePattern struct {
+	queue string
+	specs []ansi.Spec
+}
+
+// entry is a literal pattern as stored in the automaton.
+type entry struct {
+	key       string // Bytes inserted into the trie, case-folded if fold is set
+	fold      bool   // Matched against the case-folded stream
+	wholeWord bool   // Must not touch a word character on either side
+	wordStart bool   // Pattern starts with a word character
+	wordEnd   bool   // Pattern ends with a word character
+}
+
+// Automaton is an Aho-Corasick automaton compiled from the literal
+// patterns of all queues, together with their regular expressions.
+// It is immutable once built and can be shared by any number of Scanners.
+type Automaton struct {
+	next    []int32    // Transition table, indexed by state*256 + byte
+	outputs [][]int32  // Pattern indices that end in each state
+	entries []entry    // Trie keys and match options, by pattern index
+	matches [2][]Match // Pattern text and owning queues, by stream and pattern index
+	maxLen  int        // Length in bytes of the longest trie key
+	folded  bool       // Some patterns are matched case-insensitively
+	regexes []regexPattern
+	escapes []escapePattern
+	// Queues with a character map, by stream; they hear every grapheme
+	graphemes [2][]string
+}
+
+// Compile builds a single automaton for the literal patterns of the given
+// queues, covering both streams. A pattern listed by several queues with
+// the same options is stored once and reports all of them. Patterns of
+// case-insensitive queues are stored case-folded. Regular expressions are
+// collected the same way and run over a sliding window of the stream.
+func Compile(queues map[string]*config.Queue) *Automaton {
+	// Sort queue names so matches are reported in a stable order
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	a := &Automaton{}
+	index := make(map[entry]int)
+	for _, name := range names {
+		q := queues[name]
+		for _, stream := range []config.Stream{config.Input, config.Output} {
+			for _, p := range q.Patterns(stream) {
+				if p == "" {
+					continue
+				}
+				e := newEntry(p, !q.CaseSensitive(), q.WholeWord)
+				i, ok := index[e]
+				if !ok {
+					i = len(a.entries)
+					index[e] = i
+					a.entries = append(a.entries, e)
+					a.matches[config.Input] = append(a.matches[config.Input], Match{Pattern: p})
+					a.matches[config.Output] = append(a.matches[config.Output], Match{Pattern: p})
+				}
+				if m := &a.matches[stream][i]; !contains(m.Queues, name) {
+					m.Queues = append(m.Queues, name)
+				}
+				if len(e.key) > a.maxLen {
+					a.maxLen = len(e.key)
+				}
+				a.folded = a.folded || e.fold
+			}
+		}
+	}
+
+	regexIndex := make(map[string]int)
+	for _, name := range names {
+		q := queues[name]
+		for _, re := range q.Regexps {
+			i, ok := regexIndex[re.String()]
+			if !ok {
+				i = len(a.regexes)
+				regexIndex[re.String()] = i
+				a.regexes = append(a.regexes, regexPattern{re: re})
+			}
+			for _, stream := range []config.Stream{config.Input, config.Output} {
+				if q.Applies(stream) && !contains(a.regexes[i].queues[stream], name) {
+					a.regexes[i].queues[stream] = append(a.regexes[i].queues[stream], name)
+				}
+			}
+		}
+	}
+
+	for _, name := range names {
+		if q := queues[name]; len(q.Map) > 0 {
+			for _, stream := range []config.Stream{config.Input, config.Output} {
+				if q.Applies(stream) {
+					a.graphemes[stream] = append(a.graphemes[stream], name)
+				}
+			}
+		}
+	}
+
+	for _, name := range names {
+		if specs := queues[name].EscapeSpecs; len(specs) > 0 {
+			a.escapes = append(a.escapes, escapePattern{queue: name, specs: specs})
+		}
+	}
+
+	a.build()
+	return a
+}
+
+// newEntry prepares a literal pattern for insertion into the trie.
+func newEntry(pattern string, fold, wholeWord bool) entry {
+	e := entry{key: pattern, fold: fold, wholeWord: wholeWord}
+	if fold {
+		e.key = string(newFolder().fold([]byte(pattern)))
+	}
+	if wholeWord {
+		first, _ := utf8.DecodeRuneInString(pattern)
+		last, _ := utf8.DecodeLastRuneInString(pattern)
+		e.wordStart = isWordRune(first)
+		e.wordEnd = isWordRune(last)
+	}
+	return e
+}
+
+// build constructs the trie, failure links and the full transition table.
+func (a *Automaton) build() {
+	// Build the trie; -1 marks a missing edge until failure links are known
+	a.next = newState(nil)
+	a.outputs = [][]int32{nil}
+	for i, e := range a.entries {
+		state := int32(0)
+		for j := 0; j < len(e.key); j++ {
+			b := int32(e.key[j])
+			if a.next[state*256+b] < 0 {
+				a.next = newState(a.next)
+				a.outputs = append(a.outputs, nil)
+				a.next[state*256+b] = int32(len(a.outputs) - 1)
+			}
+			state = a.next[state*256+b]
+		}
+		a.outputs[state] = append(a.outputs[state], int32(i))
+	}
+
+	// Breadth-first pass turns missing edges into failure transitions and
+	// merges the outputs of each state's failure target.
+	fail := make([]int32, len(a.outputs))
+	queue := make([]int32, 0, len(a.outputs))
+	for b := int32(0); b < 256; b++ {
+		if s := a.next[b]; s > 0 {
+			queue = append(queue, s)
+		} else {
+			a.next[b] = 0
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		a.outputs[state] = append(a.outputs[state], a.outputs[fail[state]]...)
+		for b := int32(0); b < 256; b++ {
+			s := a.next[state*256+b]
+			if s < 0 {
+				a.next[state*256+b] = a.next[fail[state]*256+b]
+				continue
+			}
+			fail[s] = a.next[fail[state]*256+b]
+			queue = append(queue, s)
+		}
+	}
+}
+
+// Empty reports whether the automaton has no patterns.
+func (a *Automaton) Empty() bool {
+	return len(a.entries) == 0 && len(a.regexes) == 0 &&
+		len(a.graphemes[config.Input]) == 0 && len(a.graphemes[config.Output]) == 0
+}
+
+// MatchSequence calls fn if any queue selects the escape sequence. The
+// match pattern is the sequence in spec notation, e.g. "csi:2J".
+func (a *Automaton) MatchSequence(seq ansi.Sequence, fn func(Match)) {
+	var queues []string
+	for _, e := range a.escapes {
+		for _, spec := range e.specs {
+			if spec.Matches(seq) {
+				queues = append(queues, e.queue)
+				break
+			}
+		}
+	}
+	if len(queues) > 0 {
+		fn(Match{Pattern: seq.String(), Queues: queues})
+	}
+}
+
+// NewScanner returns a scanner positioned at the start of the given
+// stream. It only reports queues whose patterns apply to that stream.
+func (a *Automaton) NewScanner(stream config.Stream) *Scanner {
+	return &Scanner{
+		a:       a,
+		stream:  stream,
+		seg:     NewSegmenter(stream == config.Output),
+		folder:  newFolder(),
+		lastEnd: make([]int64, len(a.regexes)),
+	}
+}
+
+// Scanner runs an Automaton over a byte stream delivered in chunks. The
+// stream is decoded into grapheme clusters, and literal patterns only
+// match whole clusters, so "e" does not match inside "é" written as "e"
+// plus a combining accent. The automaton state and a window of recent
+// bytes are kept between calls to Feed, so patterns split across reads
+// are still found. A Scanner is not safe for concurrent use; create one
+// per stream direction.
+//
+// A whole-word match that ends with a word character is only reported
+// once the following cluster shows that the word has ended.
+//
+// On output, the last cluster of each read is held until the next read or
+// Flush shows it is complete, so a combining mark or emoji modifier that
+// arrives in a later read still joins it. Input is scanned without that
+// delay, so each keystroke is matched as soon as it is typed.
+type Scanner struct {
+	a       *Automaton
+	stream  config.Stream
+	seg     *Segmenter
+	folder  *folder
+	tracks  [2]track // Automaton runs over the stream as is and case-folded
+	pending []Match  // Whole-word matches waiting for the next cluster
+	ready   []byte   // Clusters completed by the current Feed
+
+	window  []byte  // Trailing stream bytes for regex matching
+	offset  int64   // Stream offset of window[0]
+	lastEnd []int64 // Stream offset where each regex last matched
+}
+
+// track is one run of the automaton over the stream.
+type track struct {
+	state  int32
+	pos    int64          // Offset after the last cluster scanned
+	starts []clusterStart // Recent clusters, oldest first
+}
+
+// clusterStart records where a grapheme cluster begins within a track.
+type clusterStart struct {
+	offset int64
+	word   bool // Cluster starts with a word character
+}
+
+// Feed scans the next chunk of the stream and calls fn for every match
+// that ends inside data.
+func (s *Scanner) Feed(data []byte, fn func(Match)) {
+	a := s.a
+	if a.Empty() {
+		return
+	}
+
+	s.ready = s.ready[:0]
+	s.seg.Feed(data, func(cluster []byte) {
+		s.scanCluster(cluster, fn)
+		if len(a.regexes) > 0 {
+			s.ready = append(s.ready, cluster...)
+		}
+	})
+
+	if len(s.ready) > 0 {
+		s.feedRegexes(s.ready, fn)
+	}
+}
+
+// Flush ends the stream: held back bytes are scanned as final clusters
+// and whole-word matches waiting for the next cluster are reported, since
+// the end of the stream also ends the word.
+func (s *Scanner) Flush(fn func(Match)) {
+	a := s.a
+	if a.Empty() {
+		return
+	}
+
+	s.ready = s.ready[:0]
+	s.seg.Flush(func(cluster []byte) {
+		s.scanCluster(cluster, fn)
+		if len(a.regexes) > 0 {
+			s.ready = append(s.ready, cluster...)
+		}
+	})
+	if len(s.ready) > 0 {
+		s.feedRegexes(s.ready, fn)
+	}
+
+	for _, m := range s.pending {
+		fn(m)
+	}
+	s.pending = s.pending[:0]
+}
+
+// scanCluster advances the automaton over one grapheme cluster and reports
+// the patterns that end with it and start on a cluster boundary.
+func (s *Scanner) scanCluster(cluster []byte, fn func(Match)) {
+	r, _ := utf8.DecodeRune(cluster)
+	word := isWordRune(r)
+
+	// The new cluster decides any whole-word match waiting on it
+	if len(s.pending) > 0 {
+		if !word {
+			for _, m := range s.pending {
+				fn(m)
+			}
+		}
+		s.pending = s.pending[:0]
+	}
+
+	// Queues with a character map get every cluster to look up
+	if queues := s.a.graphemes[s.stream]; len(queues) > 0 {
+		fn(Match{Pattern: string(cluster), Queues: queues})
+	}
+
+	s.scanTrack(&s.tracks[0], cluster, word, false, fn)
+	if s.a.folded {
+		s.scanTrack(&s.tracks[1], s.folder.fold(cluster), word, true, fn)
+	}
+}
+
+// scanTrack advances one track over a cluster and reports the patterns of
+// that track which end with it.
+func (s *Scanner) scanTrack(t *track, cluster []byte, word, folded bool, fn func(Match)) {
+	a := s.a
+	t.starts = append(t.starts, clusterStart{offset: t.pos, word: word})
+	t.pos += int64(len(cluster))
+
+	state := t.state
+	for _, b := range cluster {
+		state = a.next[state*256+int32(b)]
+	}
+	t.state = state
+
+	matches := a.matches[s.stream]
+	for _, i := range a.outputs[state] {
+		m, e := matches[i], a.entries[i]
+		if len(m.Queues) == 0 || e.fold != folded {
+			continue
+		}
+		k := t.clusterAt(t.pos - int64(len(e.key)))
+		if k < 0 {
+			continue
+		}
+		if e.wholeWord {
+			if e.wordStart && k > 0 && t.starts[k-1].word {
+				continue
+			}
+			if e.wordEnd {
+				s.pending = append(s.pending, m)
+				continue
+			}
+		}
+		fn(m)
+	}
+
+	// Forget clusters too far back for any pattern to reach, keeping the
+	// one before the oldest reachable start for word boundary checks.
+	n := 0
+	for n < len(t.starts)-1 && t.starts[n+1].offset < t.pos-int64(a.maxLen) {
+		n++
+	}
+	if n > 0 {
+		t.starts = append(t.starts[:0], t.starts[n:]...)
+	}
+}
+
+// clusterAt returns the index of the cluster starting at offset, or -1 if
+// offset falls inside a cluster.
+func (t *track) clusterAt(offset int64) int {
+	for i := len(t.starts) - 1; i >= 0; i-- {
+		if t.starts[i].offset == offset {
+			return i
+		}
+		if t.starts[i].offset < offset {
+			break
+		}
+	}
+	return -1
+}
+
+// feedRegexes runs every regex over the window extended with data. Only
+// matches that end in the new data and do not overlap an earlier match of
+// the same regex are reported.
+func (s *Scanner) feedRegexes(data []byte, fn func(Match)) {
+	newStart := len(s.window)
+	s.window = append(s.window, data...)
+
+	for i, r := range s.a.regexes {
+		queues := r.queues[s.stream]
+		if len(queues) == 0 {
+			continue
+		}
+		for _, loc := range r.re.FindAllIndex(s.window, -1) {
+			start, end := s.offset+int64(loc[0]), s.offset+int64(loc[1])
+			if loc[1] <= newStart || loc[0] == loc[1] || start < s.lastEnd[i] {
+				continue
+			}
+			s.lastEnd[i] = end
+			fn(Match{Pattern: string(s.window[loc[0]:loc[1]]), Queues: queues})
+		}
+	}
+
+	// Slide the window forward
+	if excess := len(s.window) - RegexWindow; excess > 0 {
+		s.window = append(s.window[:0], s.window[excess:]...)
+		s.offset += int64(excess)
+	}
+}
+
+// newState appends a state with no outgoing edges to the transition table.
+func newState(next []int32) []int32 {
+	for b := 0; b < 256; b++ {
+		next = append(next, -1)
+	}
+	return next
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
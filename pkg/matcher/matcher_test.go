@@ -0,0 +1,182 @@
+package matcher
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hiway/chirp/pkg/config"
+)
+
+func testQueues(t *testing.T) map[string]*config.Queue {
+	t.Helper()
+	insensitive := false
+	queues := map[string]*config.Queue{
+		"error": {Match: []string{"error:"}},
+		"warn":  {Match: []string{"Warning"}, CaseSense: &insensitive},
+		"loss":  {Match: []string{"loss"}, WholeWord: true},
+		"fail":  {Regex: []string{`\[FAIL\]`}},
+	}
+	for name, q := range queues {
+		q.Name = name
+		q.SampleName = name
+		if err := q.Validate(); err != nil {
+			t.Fatalf("queue %s: Validate() = %v", name, err)
+		}
+	}
+	return queues
+}
+
+// scan feeds the chunks to a new scanner and returns the matches as
+// "queue:pattern" strings, sorted, since regex matches are reported after
+// the literal matches of the same read.
+func scan(a *Automaton, stream config.Stream, chunks ...string) []string {
+	var got []string
+	s := a.NewScanner(stream)
+	record := func(m Match) {
+		for _, q := range m.Queues {
+			got = append(got, q+":"+m.Pattern)
+		}
+	}
+	for _, c := range chunks {
+		s.Feed([]byte(c), record)
+	}
+	s.Flush(record)
+	slices.Sort(got)
+	return got
+}
+
+func TestScannerSplitReads(t *testing.T) {
+	a := Compile(testQueues(t))
+	input := "make: error: WARNING lossless loss [FAIL] warning\n"
+	want := []string{
+		"error:error:",
+		"fail:[FAIL]",
+		"loss:loss",
+		"warn:Warning",
+		"warn:Warning",
+	}
+
+	if got := scan(a, config.Output, input); !slices.Equal(got, want) {
+		t.Fatalf("single read: got %q, want %q", got, want)
+	}
+	for i := 1; i < len(input); i++ {
+		got := scan(a, config.Output, input[:i], input[i:])
+		if !slices.Equal(got, want) {
+			t.Errorf("split at %d (%q | %q): got %q, want %q", i, input[:i], input[i:], got, want)
+		}
+	}
+}
+
+func TestScannerByteAtATime(t *testing.T) {
+	a := Compile(testQueues(t))
+	input := "error:error: loss."
+	chunks := strings.Split(input, "")
+	want := []string{"error:error:", "error:error:", "loss:loss"}
+	if got := scan(a, config.Input, chunks...); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestScannerWholeWordAtEnd(t *testing.T) {
+	a := Compile(testQueues(t))
+
+	// The end of the stream ends the word too
+	if got := scan(a, config.Output, "total loss"); !slices.Equal(got, []string{"loss:loss"}) {
+		t.Errorf("got %q, want the whole-word match at the end", got)
+	}
+	if got := scan(a, config.Output, "lossy"); len(got) != 0 {
+		t.Errorf("got %q, want no match inside a word", got)
+	}
+}
+
+func TestScannerCaseFolding(t *testing.T) {
+	insensitive := false
+	queues := map[string]*config.Queue{
+		"street": {Name: "street", Match: []string{"straße"}, CaseSense: &insensitive, SampleName: "x"},
+	}
+	a := Compile(queues)
+	for _, input := range []string{"STRASSE", "Straße", "strasse"} {
+		for i := 0; i <= len(input); i++ {
+			got := scan(a, config.Output, input[:i], input[i:])
+			if len(got) != 1 {
+				t.Errorf("%q split at %d: got %q, want one match", input, i, got)
+			}
+		}
+	}
+}
+
+func TestScannerClusterBoundaries(t *testing.T) {
+	queues := map[string]*config.Queue{
+		"e":    {Name: "e", Match: []string{"e"}, SampleName: "x"},
+		"like": {Name: "like", Match: []string{"👍"}, SampleName: "x"},
+	}
+	a := Compile(queues)
+
+	// "e" plus a combining accent is one cluster, so "e" does not match
+	if got := scan(a, config.Output, "é "); len(got) != 0 {
+		t.Errorf("decomposed é: got %q, want no match", got)
+	}
+	if got := scan(a, config.Output, "👍🏽 "); len(got) != 0 {
+		t.Errorf("skin-toned thumbs up: got %q, want no match", got)
+	}
+	if got := scan(a, config.Output, "👍 "); !slices.Equal(got, []string{"like:👍"}) {
+		t.Errorf("thumbs up: got %q, want one match", got)
+	}
+}
+
+func TestScannerDirection(t *testing.T) {
+	queues := map[string]*config.Queue{
+		"in": {Name: "in", Match: []string{"x"}, Direction: config.DirectionInput, SampleName: "x"},
+	}
+	a := Compile(queues)
+	if got := scan(a, config.Output, "x\n"); len(got) != 0 {
+		t.Errorf("output: got %q, want no match for an input queue", got)
+	}
+	if got := scan(a, config.Input, "x\n"); len(got) != 1 {
+		t.Errorf("input: got %q, want one match", got)
+	}
+}
+
+func TestScannerClusterSplitAcrossReads(t *testing.T) {
+	queues := map[string]*config.Queue{
+		"e":    {Name: "e", Match: []string{"e"}, SampleName: "x"},
+		"like": {Name: "like", Match: []string{"👍"}, SampleName: "x"},
+	}
+	a := Compile(queues)
+
+	// A combining accent in the next read still joins the "e"
+	if got := scan(a, config.Output, "ab e", "\u0301 z"); len(got) != 0 {
+		t.Errorf("accent in next read: got %q, want no match", got)
+	}
+	// So does a skin tone modifier
+	if got := scan(a, config.Output, "👍", "🏽 "); len(got) != 0 {
+		t.Errorf("modifier in next read: got %q, want no match", got)
+	}
+	// A held cluster is reported once the next read shows it is complete
+	if got := scan(a, config.Output, "ab e", " z"); !slices.Equal(got, []string{"e:e"}) {
+		t.Errorf("plain e: got %q, want one match", got)
+	}
+}
+
+func TestSegmenterHoldLast(t *testing.T) {
+	var clusters []string
+	collect := func(c []byte) { clusters = append(clusters, string(c)) }
+
+	g := NewSegmenter(true)
+	g.Feed([]byte("ab"), collect)
+	if !slices.Equal(clusters, []string{"a"}) {
+		t.Fatalf("after first read: got %q, want the last cluster held", clusters)
+	}
+	g.Feed([]byte("\u0301\n"), collect)
+	if !slices.Equal(clusters, []string{"a", "b\u0301", "\n"}) {
+		t.Errorf("after second read: got %q", clusters)
+	}
+
+	// Without holdLast, complete clusters are emitted at once
+	clusters = nil
+	NewSegmenter(false).Feed([]byte("ab"), collect)
+	if !slices.Equal(clusters, []string{"a", "b"}) {
+		t.Errorf("input segmenter: got %q, want both clusters", clusters)
+	}
+}
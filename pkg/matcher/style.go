@@ -0,0 +1,81 @@
+package matcher
+
+import (
+	"sort"
+
+	"github.com/hiway/chirp/pkg/ansi"
+	"github.com/hiway/chirp/pkg/config"
+)
+
+// styleRule is a queue's SGR style spec.
+type styleRule struct {
+	queue string
+	spec  ansi.StyleSpec
+}
+
+// StyleWatcher follows the SGR style of a terminal output stream and
+// reports when visible text is printed in a style a queue is watching.
+// Each rule fires once per run of matching text; it can fire again after
+// the style has stopped matching. A StyleWatcher is not safe for
+// concurrent use.
+type StyleWatcher struct {
+	rules  []styleRule
+	active []bool // Rule has fired for the current run
+	style  ansi.Style
+}
+
+// NewStyleWatcher creates a watcher for the SGR specs of the given queues.
+func NewStyleWatcher(queues map[string]*config.Queue) *StyleWatcher {
+	names := make([]string, 0, len(queues))
+	for name := range queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := &StyleWatcher{}
+	for _, name := range names {
+		for _, spec := range queues[name].StyleSpecs {
+			w.rules = append(w.rules, styleRule{queue: name, spec: spec})
+		}
+	}
+	w.active = make([]bool, len(w.rules))
+	return w
+}
+
+// Sequence updates the current style from an escape sequence.
+func (w *StyleWatcher) Sequence(seq ansi.Sequence) {
+	if len(w.rules) == 0 || !w.style.Update(seq) {
+		return
+	}
+	for i, r := range w.rules {
+		if !r.spec.Matches(w.style) {
+			w.active[i] = false
+		}
+	}
+}
+
+// Text calls fn for each rule whose style matches when text contains
+// visible characters. The match pattern is the spec as configured.
+func (w *StyleWatcher) Text(text []byte, fn func(Match)) {
+	if len(w.rules) == 0 || !visible(text) {
+		return
+	}
+	for i, r := range w.rules {
+		if w.active[i] || !r.spec.Matches(w.style) {
+			continue
+		}
+		w.active[i] = true
+		fn(Match{Pattern: r.spec.String(), Queues: []string{r.queue}})
+	}
+}
+
+// visible reports whether text has anything other than spaces and
+// control characters.
+func visible(text []byte) bool {
+	for _, b := range text {
+		if b > ' ' && b != 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package player
+
+import (
+	"math"
+
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+// curveSteepness sets how sharply exponential envelope segments bend.
+const curveSteepness = 5.0
+
+// envelopeLevel returns the gain of an ADSR envelope at time t seconds into
+// a sound lasting total seconds. The release always ends the sound by
+// fading the attack, decay and sustain down to silence. If the release
+// starts before the sustain is reached, the attack and decay carry on
+// under the fade, so a release as long as the sound still lets it reach
+// its peak.
+func envelopeLevel(env sample.Envelope, t, total float64) float64 {
+	level := attackDecayLevel(env, t)
+	releaseStart := math.Max(0, total-env.Release)
+	if t < releaseStart || env.Release <= 0 {
+		return level
+	}
+	progress := math.Min(1, (t-releaseStart)/env.Release)
+	return level * (1 - shape(env.Curve, progress))
+}
+
+// attackDecayLevel returns the envelope level before the release.
+func attackDecayLevel(env sample.Envelope, t float64) float64 {
+	if t < env.Attack {
+		// Attack phase
+		return shape(env.Curve, t/env.Attack)
+	}
+	if t < env.Attack+env.Decay {
+		// Decay phase
+		progress := (t - env.Attack) / env.Decay
+		return 1 - (1-env.Sustain)*shape(env.Curve, progress)
+	}
+	// Sustain phase
+	return env.Sustain
+}
+
+// shape maps linear progress from 0 to 1 onto the envelope curve. The
+// exponential curve moves quickly at first and eases into its target,
+// like a capacitor charging.
+func shape(curve string, progress float64) float64 {
+	if curve != sample.CurveExponential {
+		return progress
+	}
+	return (1 - math.Exp(-curveSteepness*progress)) / (1 - math.Exp(-curveSteepness))
+}
@@ -0,0 +1,81 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+func ms(v float64) *float64 {
+	return &v
+}
+
+// peak returns the largest absolute sample of the rendered sample.
+func peak(t *testing.T, s *sample.SampleConfig) int {
+	t.Helper()
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	buf := Render(s)
+	if buf == nil {
+		t.Fatal("Render() = nil")
+	}
+	var p int
+	for _, v := range buf.Samples {
+		p = max(p, int(v), -int(v))
+	}
+	return p
+}
+
+func TestEnvelopePeak(t *testing.T) {
+	full := 0.5 * 32767
+	tests := []struct {
+		name    string
+		attack  *float64
+		release *float64
+		curve   string
+		min     float64 // Lowest acceptable peak as a fraction of full volume
+	}{
+		{"defaults", nil, nil, "", 0.95},
+		{"release as long as the sound", nil, ms(300), "", 0.85},
+		{"release nearly as long", nil, ms(290), "", 0.85},
+		{"short attack, long release", ms(2), ms(300), "", 0.95},
+		{"exponential long release", ms(2), ms(300), sample.CurveExponential, 0.9},
+	}
+	for _, tt := range tests {
+		s := &sample.SampleConfig{
+			Name:      "chime",
+			Frequency: 440,
+			Duration:  300,
+			Volume:    0.5,
+			AttackMs:  tt.attack,
+			ReleaseMs: tt.release,
+			Curve:     tt.curve,
+		}
+		if got := float64(peak(t, s)); got < tt.min*full || got > full+1 {
+			t.Errorf("%s: peak = %.0f, want between %.0f and %.0f", tt.name, got, tt.min*full, full)
+		}
+	}
+}
+
+func TestEnvelopeLevel(t *testing.T) {
+	env := sample.Envelope{Attack: 0.1, Decay: 0.1, Sustain: 0.5, Release: 0.2, Curve: sample.CurveLinear}
+	tests := []struct {
+		t, want float64
+	}{
+		{0, 0},
+		{0.05, 0.5},
+		{0.1, 1},
+		{0.15, 0.75},
+		{0.3, 0.5},    // Sustain
+		{0.4, 0.5},    // Release starts
+		{0.5, 0.25},   // Halfway through the release
+		{0.6, 0},      // End
+		{0.45, 0.375}, // A quarter of the way
+	}
+	for _, tt := range tests {
+		if got := envelopeLevel(env, tt.t, 0.6); got < tt.want-1e-9 || got > tt.want+1e-9 {
+			t.Errorf("envelopeLevel(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package player
+
+import (
+	"math"
+	"sync"
+
+	"github.com/hiway/chirp/pkg/audio"
+)
+
+const (
+	// limiterCeiling is the highest output level the limiter allows
+	limiterCeiling = 0.98 * math.MaxInt16
+	// limiterRelease is the share of the remaining reduction the limiter
+	// recovers per frame, a 50ms time constant
+	limiterRelease = 1.0 / (0.05 * SampleRate)
+
+	// DefaultDuckLevel is the gain of lower-priority voices while a
+	// higher-priority voice plays
+	DefaultDuckLevel = 0.25
+	// duckAttack and duckRelease are the shares of the remaining change a
+	// ducked voice's gain makes per frame: a 5ms fade down and a 100ms
+	// recovery
+	duckAttack  = 1.0 / (0.005 * SampleRate)
+	duckRelease = 1.0 / (0.1 * SampleRate)
+)
+
+// voice is a sound being played by the mixer.
+type voice struct {
+	samples  []int16 // Interleaved stereo PCM, shared and never modified
+	pos      int     // Next sample to mix
+	gain     float64
+	priority int
+	duck     float64 // Current ducking gain, 0.0 to 1.0
+	done     chan struct{}
+}
+
+// Mixer sums any number of voices into one stereo 16-bit stream. It is
+// read by a single long-lived output stream, so sounds start as soon as
+// they are added and overlap freely. A peak limiter keeps the sum from
+// clipping when many voices play at once. A Mixer is safe for concurrent
+// use.
+//
+// Voices have a priority. While a voice plays, voices of lower priority
+// are ducked: faded down to the duck level, or cut if it is zero, and
+// faded back up when it ends.
+type Mixer struct {
+	mu        sync.Mutex
+	voices    []*voice
+	limiter   float64 // Current limiter gain, 0.0 to 1.0
+	duckLevel float64
+	closed    bool
+}
+
+// NewMixer creates a mixer with no voices.
+func NewMixer() *Mixer {
+	return &Mixer{limiter: 1, duckLevel: DefaultDuckLevel}
+}
+
+// SetDuckLevel sets the gain of lower-priority voices, from 0.0 (cut) to
+// 1.0 (no ducking).
+func (m *Mixer) SetDuckLevel(level float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.duckLevel = level
+}
+
+// Add starts playing a buffer at the given gain and priority. The returned
+// channel is closed when the voice has been mixed in full or the mixer is
+// closed.
+func (m *Mixer) Add(buf *audio.Buffer, gain float64, priority int) <-chan struct{} {
+	v := &voice{samples: buf.Samples, gain: gain, priority: priority, done: make(chan struct{})}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		close(v.done)
+		return v.done
+	}
+	// Start already ducked if a more important voice is playing
+	v.duck = m.duckTarget(v, m.topPriority())
+	m.voices = append(m.voices, v)
+	return v.done
+}
+
+// topPriority returns the highest priority of the voices still playing.
+// The caller must hold m.mu.
+func (m *Mixer) topPriority() int {
+	top := math.MinInt
+	for _, v := range m.voices {
+		if v.pos < len(v.samples) && v.priority > top {
+			top = v.priority
+		}
+	}
+	return top
+}
+
+// duckTarget returns the ducking gain a voice should fade to.
+func (m *Mixer) duckTarget(v *voice, top int) float64 {
+	if v.priority < top {
+		return m.duckLevel
+	}
+	return 1
+}
+
+// Active returns the number of voices still playing.
+func (m *Mixer) Active() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.voices)
+}
+
+// Read mixes the next len(p)/4 frames into p as little-endian 16-bit
+// stereo. It never blocks and produces silence when no voice is playing.
+func (m *Mixer) Read(p []byte) (int, error) {
+	frameSize := ChannelCount * BitDepthInBytes
+	frames := len(p) / frameSize
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	top := m.topPriority()
+	var sum [ChannelCount]float64
+	for f := 0; f < frames; f++ {
+		// Ease each voice's ducking gain towards its target
+		for _, v := range m.voices {
+			target := m.duckTarget(v, top)
+			if target < v.duck {
+				v.duck += (target - v.duck) * duckAttack
+			} else {
+				v.duck += (target - v.duck) * duckRelease
+			}
+		}
+
+		peak := 0.0
+		for c := range sum {
+			sum[c] = 0
+			for _, v := range m.voices {
+				if v.pos+c < len(v.samples) {
+					sum[c] += float64(v.samples[v.pos+c]) * v.gain * v.duck
+				}
+			}
+			peak = math.Max(peak, math.Abs(sum[c]))
+		}
+		for _, v := range m.voices {
+			v.pos += ChannelCount
+		}
+
+		// Ease back towards unity gain, but clamp loud peaks instantly
+		if m.limiter < 1 {
+			m.limiter = math.Min(1, m.limiter+(1-m.limiter)*limiterRelease)
+		}
+		if peak*m.limiter > limiterCeiling {
+			m.limiter = limiterCeiling / peak
+		}
+
+		for c, s := range sum {
+			v := int16(s * m.limiter)
+			i := f*frameSize + c*BitDepthInBytes
+			p[i] = byte(uint16(v))
+			p[i+1] = byte(uint16(v) >> 8)
+		}
+	}
+
+	// Retire finished voices
+	active := m.voices[:0]
+	for _, v := range m.voices {
+		if v.pos < len(v.samples) {
+			active = append(active, v)
+		} else {
+			close(v.done)
+		}
+	}
+	for i := len(active); i < len(m.voices); i++ {
+		m.voices[i] = nil
+	}
+	m.voices = active
+
+	return frames * frameSize, nil
+}
+
+// Close stops all voices and releases anyone waiting on them. Voices
+// added afterwards finish immediately.
+func (m *Mixer) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for _, v := range m.voices {
+		close(v.done)
+	}
+	m.voices = nil
+}
@@ -0,0 +1,104 @@
+package player
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/hiway/chirp/pkg/audio"
+)
+
+// constant returns a buffer holding ms milliseconds of a constant level.
+func constant(level int16, ms int) *audio.Buffer {
+	buf := &audio.Buffer{Samples: make([]int16, ms*SampleRate/1000*ChannelCount)}
+	for i := range buf.Samples {
+		buf.Samples[i] = level
+	}
+	return buf
+}
+
+// read mixes ms milliseconds and returns the left channel.
+func read(m *Mixer, ms int) []int16 {
+	frames := ms * SampleRate / 1000
+	p := make([]byte, frames*ChannelCount*BitDepthInBytes)
+	m.Read(p)
+	out := make([]int16, frames)
+	for f := range out {
+		out[f] = int16(binary.LittleEndian.Uint16(p[f*ChannelCount*BitDepthInBytes:]))
+	}
+	return out
+}
+
+// near reports whether got is within 1% of full scale of want.
+func near(got int16, want float64) bool {
+	return math.Abs(float64(got)-want) < 0.01*math.MaxInt16
+}
+
+func TestMixerDucking(t *testing.T) {
+	m := NewMixer()
+	m.Add(constant(10000, 2000), 1, 0)
+	if got := read(m, 50); !near(got[len(got)-1], 10000) {
+		t.Fatalf("level alone = %d, want 10000", got[len(got)-1])
+	}
+
+	// A silent higher-priority voice ducks the low one
+	m.Add(constant(0, 200), 1, 1)
+	got := read(m, 50)
+	if got[0] < 9000 {
+		t.Errorf("level right after the duck = %d, want a fade rather than a jump", got[0])
+	}
+	if want := 10000 * DefaultDuckLevel; !near(got[len(got)-1], want) {
+		t.Errorf("ducked level = %d, want %.0f", got[len(got)-1], want)
+	}
+
+	// Once it ends, the low voice fades back up
+	read(m, 150)
+	got = read(m, 10)
+	if got[0] > 5000 {
+		t.Errorf("level right after the duck ended = %d, want a fade rather than a jump", got[0])
+	}
+	if got = read(m, 600); !near(got[len(got)-1], 10000) {
+		t.Errorf("recovered level = %d, want 10000", got[len(got)-1])
+	}
+}
+
+func TestMixerStartsDucked(t *testing.T) {
+	m := NewMixer()
+	m.SetDuckLevel(0.5)
+	m.Add(constant(0, 500), 1, 1)
+	read(m, 10)
+
+	// A low voice added while a higher one plays starts at the duck level
+	m.Add(constant(10000, 500), 1, 0)
+	if got := read(m, 10); !near(got[0], 5000) {
+		t.Errorf("first level = %d, want 5000", got[0])
+	}
+}
+
+func TestMixerEqualPriority(t *testing.T) {
+	m := NewMixer()
+	m.Add(constant(10000, 500), 1, 1)
+	m.Add(constant(0, 500), 1, 1)
+	if got := read(m, 100); !near(got[len(got)-1], 10000) {
+		t.Errorf("level = %d, want 10000 with no ducking", got[len(got)-1])
+	}
+}
+
+func TestMixerLimiter(t *testing.T) {
+	m := NewMixer()
+	for i := 0; i < 4; i++ {
+		m.Add(constant(20000, 200), 1, 0)
+		m.Add(constant(-20000, 100), 0.5, 0)
+	}
+	for i, v := range read(m, 300) {
+		if math.Abs(float64(v)) > limiterCeiling {
+			t.Fatalf("frame %d = %d, over the ceiling %.0f", i, v, limiterCeiling)
+		}
+	}
+
+	// The limiter eases back to unity gain
+	m.Add(constant(10000, 1000), 1, 0)
+	if got := read(m, 500); !near(got[len(got)-1], 10000) {
+		t.Errorf("level after the limiter recovered = %d, want 10000", got[len(got)-1])
+	}
+}
@@ -0,0 +1,105 @@
+package player
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+// oscillator produces one waveform sample per call to next, in the range
+// -1.0 to 1.0. Square, sawtooth and pulse waves are band-limited with
+// PolyBLEP corrections, and the triangle is the integral of the
+// band-limited square, so none of them alias at SampleRate.
+type oscillator struct {
+	waveform string
+	phase    float64 // Position within the cycle, 0.0 to 1.0
+	step     float64 // Phase increment per sample (frequency / SampleRate)
+	duty     float64 // High fraction of the cycle for pulse waves
+	integral float64 // Triangle integrator state
+	pink     [3]float64
+	rng      *rand.Rand
+}
+
+// newOscillator creates an oscillator for a sample's waveform and pitch.
+func newOscillator(s *sample.SampleConfig) *oscillator {
+	o := &oscillator{
+		waveform: s.Waveform,
+		step:     s.Frequency / SampleRate,
+		duty:     0.5,
+		integral: -1 + 2*s.Frequency/SampleRate, // Low point, allowing for the rounded corner
+		rng:      rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+	if o.waveform == sample.WavePulse {
+		o.duty = s.PulseWidth()
+	}
+	return o
+}
+
+// next returns the next sample and advances the phase.
+func (o *oscillator) next() float64 {
+	var v float64
+	switch o.waveform {
+	case sample.WaveSquare, sample.WavePulse:
+		v = o.square()
+	case sample.WaveTriangle:
+		// Integrating a ±1 square over half a cycle spans exactly -1 to 1
+		o.integral += 4 * o.step * o.square()
+		v = o.integral
+	case sample.WaveSawtooth:
+		v = 2*o.phase - 1 - polyBLEP(o.phase, o.step)
+	case sample.WaveWhiteNoise:
+		v = o.white()
+	case sample.WavePinkNoise:
+		// Paul Kellet's economy pink noise filter
+		w := o.white()
+		o.pink[0] = 0.99765*o.pink[0] + w*0.0990460
+		o.pink[1] = 0.96300*o.pink[1] + w*0.2965164
+		o.pink[2] = 0.57000*o.pink[2] + w*1.0526913
+		v = (o.pink[0] + o.pink[1] + o.pink[2] + w*0.1848) * 0.25
+	default:
+		v = math.Sin(2 * math.Pi * o.phase)
+	}
+
+	o.phase += o.step
+	if o.phase >= 1 {
+		o.phase -= math.Floor(o.phase)
+	}
+	return math.Max(-1, math.Min(1, v))
+}
+
+// square returns the band-limited square or pulse value at the current
+// phase, centred on zero. Removing the DC offset of a narrow pulse moves
+// it off centre, so it is scaled back into -1.0 to 1.0 rather than
+// clipped, which would bring the offset back.
+func (o *oscillator) square() float64 {
+	v := -1.0
+	if o.phase < o.duty {
+		v = 1.0
+	}
+	v += polyBLEP(o.phase, o.step)
+	v -= polyBLEP(math.Mod(o.phase+1-o.duty, 1), o.step)
+	v -= 2*o.duty - 1
+	return v / (2 * math.Max(o.duty, 1-o.duty))
+}
+
+func (o *oscillator) white() float64 {
+	return o.rng.Float64()*2 - 1
+}
+
+// polyBLEP returns the correction for a unit step at phase 0, given the
+// phase t and phase increment dt.
+func polyBLEP(t, dt float64) float64 {
+	if dt <= 0 {
+		return 0
+	}
+	if t < dt {
+		t /= dt
+		return t + t - t*t - 1
+	}
+	if t > 1-dt {
+		t = (t - 1) / dt
+		return t*t + t + t + 1
+	}
+	return 0
+}
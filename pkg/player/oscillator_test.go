@@ -0,0 +1,28 @@
+package player
+
+import (
+	"testing"
+
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+func TestPulseRange(t *testing.T) {
+	for _, duty := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+		// 480 Hz is exactly 100 samples per cycle
+		s := &sample.SampleConfig{Waveform: sample.WavePulse, Frequency: 480, Duty: duty}
+		o := newOscillator(s)
+		var sum, lo, hi float64
+		const n = 100 * 100
+		for i := 0; i < n; i++ {
+			v := o.next()
+			sum += v
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if mean := sum / n; mean < -0.005 || mean > 0.005 {
+			t.Errorf("duty %v: mean = %.4f, want 0", duty, mean)
+		}
+		if lo < -1 || hi > 1 || max(-lo, hi) < 0.99 {
+			t.Errorf("duty %v: range = %.3f to %.3f, want a peak of 1.0", duty, lo, hi)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package otoplayer plays sounds through the sound card with the
+// ebitengine/oto/v3 library. It is kept apart from package player, as oto
+// links the system audio libraries through cgo.
+package otoplayer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ebitengine/oto/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/hiway/chirp/pkg/player"
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+var (
+	otoCtx *oto.Context
+	once   sync.Once
+	ctxErr error
+)
+
+// initOtoContext initializes the oto context singleton.
+func initOtoContext() (*oto.Context, error) {
+	once.Do(func() {
+		op := &oto.NewContextOptions{}
+		op.SampleRate = player.SampleRate
+		op.ChannelCount = player.ChannelCount
+		op.Format = oto.FormatSignedInt16LE
+		// BufferSize is calculated by Oto based on SampleRate, ChannelCount, and Format.
+		// We don't need to set op.BufferSize explicitly unless overriding.
+
+		var readyChan chan struct{}
+		otoCtx, readyChan, ctxErr = oto.NewContext(op)
+		if ctxErr == nil {
+			<-readyChan // Wait for the context to be ready
+		}
+	})
+	return otoCtx, ctxErr
+}
+
+// Player uses the ebitengine/oto/v3 library to play sounds. All sounds
+// go through one long-lived output stream fed by a player.Mixer, so sounds
+// from different queues play in parallel.
+type Player struct {
+	log    zerolog.Logger
+	ctx    *oto.Context
+	cache  *player.Cache
+	mixer  *player.Mixer
+	stream *oto.Player
+}
+
+// New creates a new player using the Oto library.
+func New(log zerolog.Logger) (*Player, error) {
+	ctx, err := initOtoContext()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to initialize Oto audio context")
+		return nil, fmt.Errorf("failed to initialize audio context: %w", err)
+	}
+	log.Debug().Msg("Oto audio context initialized successfully")
+
+	mixer := player.NewMixer()
+	stream := ctx.NewPlayer(mixer)
+	// Keep the output buffer short so new voices are heard quickly
+	stream.SetBufferSize(player.BufferSizeSamples * player.ChannelCount * player.BitDepthInBytes)
+	stream.Play()
+
+	return &Player{
+		log:    log.With().Str("player_type", "oto").Logger(),
+		ctx:    ctx,
+		cache:  player.NewCache(),
+		mixer:  mixer,
+		stream: stream,
+	}, nil
+}
+
+// Prerender renders the given samples ahead of playback.
+func (p *Player) Prerender(samples map[string]*sample.SampleConfig) {
+	p.cache.Prerender(samples)
+	p.log.Debug().Int("samples", len(samples)).Msg("Prerendered samples")
+}
+
+// Play plays the rendered audio for the given sample, rendering it on
+// first use. It blocks until the sound has finished, while sounds started
+// by other goroutines are mixed in alongside it, ducked if their priority
+// is lower.
+func (p *Player) Play(sample *sample.SampleConfig, priority int) error {
+	p.log.Debug().
+		Str("sample_name", sample.Name).
+		Dur("duration", sample.Length()).
+		Float64("frequency_hz", sample.Frequency).
+		Str("file", sample.File).
+		Float64("volume", sample.Volume).
+		Int("priority", priority).
+		Msg("Playing sample")
+
+	// Fetch the rendered audio, synthesizing it on first use
+	data := p.cache.Get(sample)
+	if data == nil {
+		p.log.Debug().Str("sample_name", sample.Name).Msg("Skipping playback for zero-volume or zero-duration sample")
+		return nil // Nothing to play
+	}
+
+	// Mix the sound in and wait for it to finish
+	<-p.mixer.Add(data, 1.0, priority)
+	if err := p.stream.Err(); err != nil {
+		p.log.Error().Err(err).Str("sample_name", sample.Name).Msg("Failed to play sound")
+		return fmt.Errorf("failed to play sound for sample '%s': %w", sample.Name, err)
+	}
+
+	p.log.Trace().Str("sample_name", sample.Name).Msg("Finished playing sample")
+	return nil
+}
+
+// SetDuckLevel sets the gain of lower-priority sounds, from 0.0 (cut) to
+// 1.0 (no ducking).
+func (p *Player) SetDuckLevel(level float64) {
+	p.mixer.SetDuckLevel(level)
+}
+
+// Close stops the output stream and releases any waiting Play calls.
+func (p *Player) Close() error {
+	p.log.Debug().Msg("Closing Oto player")
+	p.mixer.Close()
+	// The Oto context is global and shared, so we only close our stream.
+	if err := p.stream.Close(); err != nil {
+		return fmt.Errorf("failed to close audio stream: %w", err)
+	}
+	return nil
+}
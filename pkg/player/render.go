@@ -0,0 +1,157 @@
+package player
+
+import (
+	"sync"
+
+	"github.com/hiway/chirp/pkg/audio"
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+// fadeOutSamples is the length of the fade applied when a sound file is cut
+// short by the sample duration, to avoid a click.
+const fadeOutSamples = SampleRate / 200 // 5ms
+
+// Prerenderer is implemented by players that can render samples ahead of
+// playback, taking synthesis off the hot path.
+type Prerenderer interface {
+	Prerender(samples map[string]*sample.SampleConfig)
+}
+
+// Render produces the PCM audio for a sample: its sound file at the
+// sample's volume, or a synthesized tone. It returns nil if there is
+// nothing to play.
+func Render(s *sample.SampleConfig) *audio.Buffer {
+	if s.PCM != nil {
+		return renderFile(s)
+	}
+	return renderTone(s)
+}
+
+// renderTone creates a tone in the sample's waveform with ADSR envelope based on SampleConfig.
+func renderTone(s *sample.SampleConfig) *audio.Buffer {
+	if s.Volume <= 0 || s.Duration <= 0 {
+		return nil // Nothing to generate
+	}
+
+	duration := s.Length()
+	sampleRate := float64(SampleRate)
+	numSamples := int(duration.Seconds() * sampleRate)
+	data := make([]int16, numSamples*ChannelCount)
+
+	// ADSR parameters (in seconds)
+	env := s.Envelope()
+	total := duration.Seconds()
+
+	// Pre-calculate amplitude
+	osc := newOscillator(s)
+	amplitude := s.Volume * 32767.0 // Scale to 16-bit range
+
+	for i := 0; i < numSamples; i++ {
+		// Calculate envelope
+		envelope := envelopeLevel(env, float64(i)/sampleRate, total)
+
+		// Generate sample value
+		value := int16(amplitude * envelope * osc.next())
+
+		// Stereo output (write same value to both channels)
+		data[i*ChannelCount] = value   // Left channel
+		data[i*ChannelCount+1] = value // Right channel
+	}
+	return &audio.Buffer{Samples: data}
+}
+
+// renderFile returns the decoded sound file of a sample at the sample's
+// volume and pitch, limited to its duration.
+func renderFile(s *sample.SampleConfig) *audio.Buffer {
+	if s.Volume <= 0 {
+		return nil
+	}
+	pcm := s.PCM
+	if pitch := s.PitchFactor(); pitch != 1 {
+		pcm = pcm.Repitched(pitch)
+	}
+	src := pcm.Samples
+	frames := pcm.Frames()
+	cut := false
+	if limit := int(s.Length().Seconds() * SampleRate); limit < frames {
+		frames, cut = limit, true
+	}
+	if frames == 0 {
+		return nil
+	}
+
+	data := make([]int16, frames*ChannelCount)
+	for i := 0; i < frames; i++ {
+		gain := s.Volume
+		if remaining := frames - i; cut && remaining < fadeOutSamples {
+			gain *= float64(remaining) / fadeOutSamples
+		}
+		for c := 0; c < ChannelCount; c++ {
+			data[i*ChannelCount+c] = int16(float64(src[i*ChannelCount+c]) * gain)
+		}
+	}
+	return &audio.Buffer{Samples: data}
+}
+
+// renderKey holds every sample setting that affects the rendered audio, so
+// a changed configuration never hits a stale entry.
+type renderKey struct {
+	duration  int
+	frequency float64
+	volume    float64
+	waveform  string
+	duty      float64
+	envelope  sample.Envelope
+	pcm       *audio.Buffer
+	pitch     float64
+}
+
+func keyOf(s *sample.SampleConfig) renderKey {
+	return renderKey{
+		duration:  s.Duration,
+		frequency: s.Frequency,
+		volume:    s.Volume,
+		waveform:  s.Waveform,
+		duty:      s.PulseWidth(),
+		envelope:  s.Envelope(),
+		pcm:       s.PCM,
+		pitch:     s.PitchFactor(),
+	}
+}
+
+// Cache holds rendered samples. Buffers are immutable once rendered and
+// may be shared by concurrent players. A Cache is safe for concurrent use.
+type Cache struct {
+	mu      sync.RWMutex
+	entries map[renderKey]*audio.Buffer
+}
+
+// NewCache creates an empty render cache.
+func NewCache() *Cache {
+	return &Cache{entries: make(map[renderKey]*audio.Buffer)}
+}
+
+// Get returns the rendered audio for a sample, rendering it on first use.
+// It returns nil if there is nothing to play.
+func (c *Cache) Get(s *sample.SampleConfig) *audio.Buffer {
+	key := keyOf(s)
+	c.mu.RLock()
+	buf, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok {
+		return buf
+	}
+
+	buf = Render(s)
+	c.mu.Lock()
+	c.entries[key] = buf
+	c.mu.Unlock()
+	return buf
+}
+
+// Prerender renders every given sample into the cache.
+func (c *Cache) Prerender(samples map[string]*sample.SampleConfig) {
+	for _, s := range samples {
+		c.Get(s)
+	}
+}
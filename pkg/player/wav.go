@@ -0,0 +1,196 @@
+package player
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/hiway/chirp/pkg/audio"
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+const (
+	// wavTick is how often the WAV player writes the mixed timeline
+	wavTick = 10 * time.Millisecond
+	// wavDrainLimit bounds how long Close keeps rendering voices still playing
+	wavDrainLimit = 10 * time.Second
+	// wavMaxChunk is the most frames rendered per tick, so catching up
+	// after a stall takes bounded memory and Close stays responsive
+	wavMaxChunk = SampleRate
+)
+
+// WavPlayer renders sounds into a WAV file instead of playing them. The
+// file is a timeline of the whole session in real time: silence between
+// sounds is kept, and sounds that overlap are mixed just as the oto
+// player would play them. It needs no audio device.
+type WavPlayer struct {
+	log     zerolog.Logger
+	file    *os.File
+	wav     *audio.WAVWriter
+	cache   *Cache
+	mixer   *Mixer
+	start   time.Time
+	written int64 // Frames written to the file
+	buf     []byte
+
+	mu        sync.Mutex // Protects file writes
+	stopOnce  sync.Once
+	stopChan  chan struct{}
+	doneChan  chan struct{}
+	closeErr  error
+	writeErrs int
+}
+
+// NewWavPlayer creates a player that writes the session to a WAV file at
+// path, replacing any existing file.
+func NewWavPlayer(path string, log zerolog.Logger) (*WavPlayer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio output file: %w", err)
+	}
+	wav, err := audio.NewWAVWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	p := &WavPlayer{
+		log:      log.With().Str("player_type", "wav").Str("path", path).Logger(),
+		file:     f,
+		wav:      wav,
+		cache:    NewCache(),
+		mixer:    NewMixer(),
+		start:    time.Now(),
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+	go p.run()
+
+	p.log.Debug().Msg("Writing audio to WAV file")
+	return p, nil
+}
+
+// CheckWavOutput reports whether a WAV file could be created at path,
+// without touching any existing file there.
+func CheckWavOutput(path string) error {
+	if path == "" {
+		return fmt.Errorf("no audio output file set")
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".chirp-check-*")
+	if err != nil {
+		return fmt.Errorf("cannot write audio output file: %w", err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// Prerender renders the given samples ahead of playback.
+func (p *WavPlayer) Prerender(samples map[string]*sample.SampleConfig) {
+	p.cache.Prerender(samples)
+	p.log.Debug().Int("samples", len(samples)).Msg("Prerendered samples")
+}
+
+// Play places the sample on the timeline at the current time and blocks
+// until it has been written, like the oto player.
+func (p *WavPlayer) Play(sample *sample.SampleConfig, priority int) error {
+	p.log.Debug().
+		Str("sample_name", sample.Name).
+		Dur("duration", sample.Length()).
+		Float64("frequency_hz", sample.Frequency).
+		Str("file", sample.File).
+		Float64("volume", sample.Volume).
+		Int("priority", priority).
+		Msg("Rendering sample")
+
+	data := p.cache.Get(sample)
+	if data == nil {
+		p.log.Debug().Str("sample_name", sample.Name).Msg("Skipping zero-volume or zero-duration sample")
+		return nil // Nothing to play
+	}
+
+	// Mix the sound in and wait for it to be written
+	<-p.mixer.Add(data, 1.0, priority)
+	p.log.Trace().Str("sample_name", sample.Name).Msg("Finished rendering sample")
+	return nil
+}
+
+// SetDuckLevel sets the gain of lower-priority sounds, from 0.0 (cut) to
+// 1.0 (no ducking).
+func (p *WavPlayer) SetDuckLevel(level float64) {
+	p.mixer.SetDuckLevel(level)
+}
+
+// run writes the mixer output to the file in step with the wall clock.
+// After a stall, such as a suspended process, it catches up one chunk per
+// tick.
+func (p *WavPlayer) run() {
+	defer close(p.doneChan)
+	ticker := time.NewTicker(wavTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case now := <-ticker.C:
+			target := int64(now.Sub(p.start).Seconds() * SampleRate)
+			p.render(min(target-p.written, wavMaxChunk))
+		}
+	}
+}
+
+// render mixes and writes the given number of frames.
+func (p *WavPlayer) render(frames int64) {
+	if frames <= 0 {
+		return
+	}
+	size := int(frames) * ChannelCount * BitDepthInBytes
+	if cap(p.buf) < size {
+		p.buf = make([]byte, size)
+	}
+	buf := p.buf[:size]
+	p.mixer.Read(buf)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.wav.Write(buf); err != nil {
+		p.writeErrs++
+		if p.writeErrs == 1 {
+			p.log.Error().Err(err).Msg("Failed to write audio output file")
+		}
+	}
+	p.written += frames
+}
+
+// Close lets sounds that are still playing finish, completes the WAV
+// header and closes the file.
+func (p *WavPlayer) Close() error {
+	p.stopOnce.Do(func() {
+		p.log.Debug().Msg("Closing WavPlayer")
+		close(p.stopChan)
+		<-p.doneChan
+
+		// Render the tail of any sound still playing
+		tick := int64(wavTick.Seconds() * SampleRate)
+		for drained := time.Duration(0); p.mixer.Active() > 0 && drained < wavDrainLimit; drained += wavTick {
+			p.render(tick)
+		}
+		p.mixer.Close()
+
+		if err := p.wav.Close(); err != nil {
+			p.closeErr = err
+		}
+		if err := p.file.Close(); err != nil && p.closeErr == nil {
+			p.closeErr = fmt.Errorf("failed to close audio output file: %w", err)
+		}
+		p.log.Info().
+			Dur("length", time.Duration(p.written)*time.Second/SampleRate).
+			Msg("Wrote audio output file")
+	})
+	return p.closeErr
+}
@@ -0,0 +1,26 @@
+package queue
+
+import "time"
+
+// Clock tells the time for rate limiting and debouncing. Tests replace it
+// with a fake one.
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call scheduled by Clock.AfterFunc.
+type Timer interface {
+	Stop() bool
+}
+
+// realClock is the Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
@@ -3,25 +3,56 @@ package queue
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 
 	"github.com/hiway/chirp/pkg/config"
 	"github.com/hiway/chirp/pkg/player"
+	"github.com/hiway/chirp/pkg/sample"
 )
 
+// Item is a matched pattern waiting to be played.
+type Item struct {
+	Text  string // Matched text
+	Count int    // Number of matches merged into this item by coalescing or debouncing, for escalation
+}
+
 // Queue manages pattern matching and sound triggering for a set of patterns.
 type Queue struct {
-	Config   *config.Queue
-	player   player.Player
-	log      zerolog.Logger
-	itemChan chan string
+	Config *config.Queue
+	player player.Player
+	log    zerolog.Logger
+	clock  Clock
+
+	mu           sync.Mutex
+	lastAccepted time.Time   // When the last item passed the rate limits
+	accepted     []time.Time // Acceptances within the last second, for max_per_second
+	debounce     *Item       // Item waiting for matches to pause
+	debounceGen  int         // Identifies the current debounce timer
+	timer        Timer       // Fires when the debounce period ends
+
+	idle    *sync.Cond // Signaled when the queue empties or stops
+	items   []Item     // Waiting items, oldest first
+	playing bool       // An item is being played
+	stopped bool
+	notify  chan struct{} // Wakes run when items are added
+
+	// Escalation state, used only by run
+	burstMatches int       // Matches played in the current burst
+	lastPlayed   time.Time // When the last sound started
+
 	stopOnce sync.Once
 	stopChan chan struct{}
 }
 
 // NewQueue creates a new queue with the given configuration.
 func NewQueue(cfg *config.Queue, player player.Player, log zerolog.Logger) (*Queue, error) {
+	return newQueue(cfg, player, log, realClock{})
+}
+
+// newQueue creates a queue that reads the time from clock.
+func newQueue(cfg *config.Queue, player player.Player, log zerolog.Logger, clock Clock) (*Queue, error) {
 	if cfg.Sample == nil {
 		return nil, fmt.Errorf("queue '%s' has nil sample configuration", cfg.Name)
 	}
@@ -30,9 +61,12 @@ func NewQueue(cfg *config.Queue, player player.Player, log zerolog.Logger) (*Que
 		Config:   cfg,
 		player:   player,
 		log:      log.With().Str("queue", cfg.Name).Logger(),
-		itemChan: make(chan string, cfg.MaxLength),
+		clock:    clock,
+		items:    make([]Item, 0, cfg.MaxLength),
+		notify:   make(chan struct{}, 1),
 		stopChan: make(chan struct{}),
 	}
+	q.idle = sync.NewCond(&q.mu)
 
 	// Start the sound playing goroutine
 	go q.run()
@@ -40,13 +74,139 @@ func NewQueue(cfg *config.Queue, player player.Player, log zerolog.Logger) (*Que
 	return q, nil
 }
 
-// Add attempts to queue a matched item for playback.
-func (q *Queue) Add(item string) {
-	select {
-	case q.itemChan <- item:
-		q.log.Trace().Str("item", item).Msg("Item added to queue")
+// Add attempts to queue a matched item for playback. With a character
+// map, graphemes no rule covers are ignored. With debounce_ms,
+// matches are held until they pause and then queued as one item. Items
+// within cooldown_ms of the last accepted one, or over max_per_second,
+// are dropped. When the queue is full, the overflow policy decides what
+// happens to the item.
+func (q *Queue) Add(text string) {
+	// A character map only hears the graphemes it covers
+	if len(q.Config.Map) > 0 {
+		if _, _, ok := q.Config.Lookup(text); !ok {
+			return
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped {
+		return
+	}
+
+	if q.Config.DebounceMs > 0 {
+		if q.debounce == nil {
+			q.debounce = &Item{Text: text}
+		}
+		q.debounce.Text = text
+		q.debounce.Count++
+		// Restart the debounce period
+		if q.timer != nil {
+			q.timer.Stop()
+		}
+		q.debounceGen++
+		gen := q.debounceGen
+		q.timer = q.clock.AfterFunc(time.Duration(q.Config.DebounceMs)*time.Millisecond, func() {
+			q.flushDebounce(gen)
+		})
+		return
+	}
+	q.admit(Item{Text: text, Count: 1})
+}
+
+// flushDebounce queues the held item once matches have paused. Calls
+// from timers that were restarted are ignored.
+func (q *Queue) flushDebounce(gen int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped || q.debounce == nil || gen != q.debounceGen {
+		return
+	}
+	item := *q.debounce
+	q.debounce = nil
+	q.timer = nil
+	q.admit(item)
+	q.idle.Broadcast() // Drain may be waiting on the debounce
+}
+
+// allow applies the cooldown and rate limit, recording the item as
+// accepted if it passes. The caller must hold q.mu.
+func (q *Queue) allow(text string) bool {
+	cooldown := time.Duration(q.Config.CooldownMs) * time.Millisecond
+	limit := q.Config.MaxPerSecond
+	if cooldown <= 0 && limit <= 0 {
+		return true
+	}
+	now := q.clock.Now()
+
+	if cooldown > 0 && !q.lastAccepted.IsZero() && now.Sub(q.lastAccepted) < cooldown {
+		q.log.Trace().Str("item", text).Msg("In cooldown, dropping item")
+		return false
+	}
+
+	if limit > 0 {
+		// Forget acceptances that have left the one second window
+		old := 0
+		for old < len(q.accepted) && now.Sub(q.accepted[old]) >= time.Second {
+			old++
+		}
+		q.accepted = append(q.accepted[:0], q.accepted[old:]...)
+		if len(q.accepted) >= limit {
+			q.log.Trace().Str("item", text).Msg("Over rate limit, dropping item")
+			return false
+		}
+		q.accepted = append(q.accepted, now)
+	}
+
+	q.lastAccepted = now
+	return true
+}
+
+// admit queues an item that has been matched, subject to the rate limits
+// and overflow policy. The caller must hold q.mu.
+func (q *Queue) admit(item Item) {
+	text := item.Text
+	if !q.allow(text) {
+		return
+	}
+
+	switch {
+	case len(q.items) < q.Config.MaxLength:
+		q.items = append(q.items, item)
+		q.log.Trace().Str("item", text).Int("count", item.Count).Msg("Item added to queue")
+	case q.Config.Overflow == config.OverflowDropOldest:
+		q.log.Debug().Str("item", q.items[0].Text).Msg("Queue full, dropping oldest item")
+		copy(q.items, q.items[1:])
+		q.items[len(q.items)-1] = item
+	case q.Config.Overflow == config.OverflowCoalesce:
+		// Fold the burst into the newest waiting item
+		last := &q.items[len(q.items)-1]
+		last.Text = text
+		last.Count += item.Count
+		q.log.Trace().Str("item", text).Int("count", last.Count).Msg("Queue full, coalescing item")
+		return
+	case q.Config.Overflow == config.OverflowReplaceLatest:
+		q.log.Debug().Str("item", q.items[len(q.items)-1].Text).Msg("Queue full, replacing latest item")
+		q.items[len(q.items)-1] = item
+		return
 	default:
-		q.log.Debug().Str("item", item).Msg("Queue full, dropping item")
+		q.log.Debug().Str("item", text).Msg("Queue full, dropping item")
+		return
+	}
+
+	select {
+	case q.notify <- struct{}{}:
+	default: // run is already due to wake
+	}
+}
+
+// Drain blocks until every queued item, including one being debounced,
+// has been played, or the queue is stopped.
+func (q *Queue) Drain() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for !q.stopped && (len(q.items) > 0 || q.playing || q.debounce != nil) {
+		q.idle.Wait()
 	}
 }
 
@@ -55,9 +215,80 @@ func (q *Queue) Stop() {
 	q.stopOnce.Do(func() {
 		q.log.Debug().Msg("Stopping queue")
 		close(q.stopChan)
+		q.mu.Lock()
+		q.stopped = true
+		if q.timer != nil {
+			q.timer.Stop()
+		}
+		q.idle.Broadcast()
+		q.mu.Unlock()
 	})
 }
 
+// next takes the oldest waiting item, if any.
+func (q *Queue) next() (Item, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.playing = len(q.items) > 0
+	if !q.playing {
+		q.idle.Broadcast()
+		return Item{}, false
+	}
+	item := q.items[0]
+	copy(q.items, q.items[1:])
+	q.items = q.items[:len(q.items)-1]
+	return item, true
+}
+
+// sampleFor returns the sample to play for an item. A character map picks
+// the sample and pitch from the item's grapheme. With escalation, each
+// match in a burst raises the pitch by another step, up to the cap; an
+// item standing for several coalesced matches takes the step of the last.
+// A pause of reset_ms starts a new burst.
+func (q *Queue) sampleFor(item Item) *sample.SampleConfig {
+	s := q.mapped(item.Text)
+	esc := q.Config.Escalate
+	if esc == nil {
+		return s
+	}
+
+	now := q.clock.Now()
+	if q.lastPlayed.IsZero() || now.Sub(q.lastPlayed) >= time.Duration(esc.ResetMs)*time.Millisecond {
+		q.burstMatches = 0
+	}
+	q.lastPlayed = now
+	q.burstMatches += item.Count
+
+	step := min(q.burstMatches-1, esc.MaxSteps)
+	if step <= 0 {
+		return s
+	}
+	q.log.Trace().Int("step", step).Msg("Escalating pitch")
+	return s.Transposed(float64(step) * esc.Semitones)
+}
+
+// mapped returns the queue's sample, or for a character map the sample
+// of the rule covering the grapheme, moved to the grapheme's pitch.
+func (q *Queue) mapped(text string) *sample.SampleConfig {
+	rule, index, ok := q.Config.Lookup(text)
+	if !ok {
+		return q.Config.Sample
+	}
+	s := q.Config.Sample
+	if rule.Sample != nil {
+		s = rule.Sample
+	}
+	if len(rule.Steps) == 0 {
+		return s // Timbre only
+	}
+	if rule.RootFreq > 0 && s.PCM == nil {
+		rooted := *s
+		rooted.Frequency = rule.RootFreq
+		s = &rooted
+	}
+	return s.Transposed(rule.Semitones(index))
+}
+
 // run processes queued items and triggers sounds.
 func (q *Queue) run() {
 	q.log.Debug().Msg("Queue processor started")
@@ -67,16 +298,31 @@ func (q *Queue) run() {
 		select {
 		case <-q.stopChan:
 			return
-		case item := <-q.itemChan:
+		case <-q.notify:
+		}
+
+		for {
+			item, ok := q.next()
+			if !ok {
+				break
+			}
+			s := q.sampleFor(item)
 			q.log.Trace().
-				Str("item", item).
-				Int("frequency", q.Config.Sample.Frequency).
-				Int("duration", q.Config.Sample.Duration).
-				Float64("volume", q.Config.Sample.Volume).
+				Str("item", item.Text).
+				Int("count", item.Count).
+				Float64("frequency", s.Frequency).
+				Dur("duration", s.Length()).
+				Float64("volume", s.Volume).
 				Msg("Playing sound for queued item")
 
-			if err := q.player.Play(q.Config.Sample); err != nil {
-				q.log.Error().Err(err).Str("item", item).Msg("Failed to play sound")
+			if err := q.player.Play(s, q.Config.Priority); err != nil {
+				q.log.Error().Err(err).Str("item", item.Text).Msg("Failed to play sound")
+			}
+
+			select {
+			case <-q.stopChan:
+				return
+			default:
 			}
 		}
 	}
@@ -0,0 +1,372 @@
+package queue
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/hiway/chirp/pkg/config"
+	"github.com/hiway/chirp/pkg/sample"
+)
+
+// fakeClock is a Clock that only moves when told to.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock   *fakeClock
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+// Advance moves the clock forward and runs the timers that come due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var due []*fakeTimer
+	for _, t := range c.timers {
+		if !t.stopped && !t.at.After(c.now) {
+			t.stopped = true
+			due = append(due, t)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// countingPlayer counts the sounds played and records their frequencies.
+// If gate is set, each sound plays until a value is received from it.
+type countingPlayer struct {
+	plays atomic.Int32
+	gate  chan struct{}
+
+	mu    sync.Mutex
+	freqs []float64
+}
+
+func (p *countingPlayer) Play(s *sample.SampleConfig, _ int) error {
+	p.plays.Add(1)
+	p.mu.Lock()
+	p.freqs = append(p.freqs, s.Frequency)
+	p.mu.Unlock()
+	if p.gate != nil {
+		<-p.gate
+	}
+	return nil
+}
+
+// semitones returns the pitch of each sound played relative to base.
+func (p *countingPlayer) semitones(base float64) []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var st []int
+	for _, f := range p.freqs {
+		st = append(st, int(math.Round(12*math.Log2(f/base))))
+	}
+	return st
+}
+
+func (p *countingPlayer) Close() error {
+	return nil
+}
+
+func newTestQueue(t *testing.T, cfg *config.Queue) (*Queue, *countingPlayer, *fakeClock) {
+	t.Helper()
+	cfg.Name = "test"
+	if len(cfg.Map) == 0 {
+		cfg.Match = []string{"x"}
+	}
+	cfg.SampleName = "test"
+	cfg.Sample = &sample.SampleConfig{Name: "test", Frequency: 440}
+	if cfg.MaxLength == 0 {
+		cfg.MaxLength = 1000 // Large enough that overflow never applies
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	p := &countingPlayer{}
+	clock := newFakeClock()
+	q, err := newQueue(cfg, p, zerolog.Nop(), clock)
+	if err != nil {
+		t.Fatalf("newQueue() = %v", err)
+	}
+	t.Cleanup(q.Stop)
+	return q, p, clock
+}
+
+// waitPlaying waits until the queue has taken its only item and is
+// playing it.
+func waitPlaying(t *testing.T, q *Queue) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		q.mu.Lock()
+		playing := q.playing && len(q.items) == 0
+		q.mu.Unlock()
+		if playing {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("queue did not start playing")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   []Item
+	}{
+		{config.OverflowDropNewest, []Item{{"b", 1}, {"c", 1}}},
+		{config.OverflowDropOldest, []Item{{"c", 1}, {"d", 1}}},
+		{config.OverflowReplaceLatest, []Item{{"b", 1}, {"d", 1}}},
+		{config.OverflowCoalesce, []Item{{"b", 1}, {"d", 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			q, p, _ := newTestQueue(t, &config.Queue{MaxLength: 2, Overflow: tt.policy})
+			p.gate = make(chan struct{})
+			t.Cleanup(func() { close(p.gate) })
+
+			// Hold "a" in the player, fill the queue, then overflow it
+			q.Add("a")
+			waitPlaying(t, q)
+			for _, text := range []string{"b", "c", "d"} {
+				q.Add(text)
+			}
+
+			q.mu.Lock()
+			got := slices.Clone(q.items)
+			q.mu.Unlock()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	q, p, clock := newTestQueue(t, &config.Queue{CooldownMs: 100})
+
+	// Accepted at 0 and 100ms; 50ms and 150ms fall in a cooldown
+	for i := 0; i < 4; i++ {
+		q.Add("x")
+		clock.Advance(50 * time.Millisecond)
+	}
+	q.Drain()
+
+	if got := p.plays.Load(); got != 2 {
+		t.Errorf("plays = %d, want 2", got)
+	}
+}
+
+func TestCooldownBurst(t *testing.T) {
+	q, p, clock := newTestQueue(t, &config.Queue{CooldownMs: 1000})
+
+	// A burst of failing test lines chimes once
+	for i := 0; i < 300; i++ {
+		q.Add("FAIL")
+		clock.Advance(time.Millisecond)
+	}
+	q.Drain()
+	if got := p.plays.Load(); got != 1 {
+		t.Errorf("plays after burst = %d, want 1", got)
+	}
+
+	// The next burst after the cooldown chimes again
+	clock.Advance(time.Second)
+	q.Add("FAIL")
+	q.Drain()
+	if got := p.plays.Load(); got != 2 {
+		t.Errorf("plays after second burst = %d, want 2", got)
+	}
+}
+
+func TestMaxPerSecond(t *testing.T) {
+	q, p, clock := newTestQueue(t, &config.Queue{MaxPerSecond: 3})
+
+	for i := 0; i < 5; i++ {
+		q.Add("x")
+	}
+	q.Drain()
+	if got := p.plays.Load(); got != 3 {
+		t.Fatalf("plays in first second = %d, want 3", got)
+	}
+
+	// Still within the same one second window
+	clock.Advance(999 * time.Millisecond)
+	q.Add("x")
+	q.Drain()
+	if got := p.plays.Load(); got != 3 {
+		t.Fatalf("plays before window ends = %d, want 3", got)
+	}
+
+	// The first three acceptances have left the window
+	clock.Advance(time.Millisecond)
+	for i := 0; i < 5; i++ {
+		q.Add("x")
+	}
+	q.Drain()
+	if got := p.plays.Load(); got != 6 {
+		t.Errorf("plays after window = %d, want 6", got)
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	q, p, clock := newTestQueue(t, &config.Queue{DebounceMs: 50})
+
+	// Matches 10ms apart keep restarting the debounce period
+	for i := 0; i < 20; i++ {
+		q.Add("x")
+		clock.Advance(10 * time.Millisecond)
+	}
+	q.mu.Lock()
+	held := q.debounce
+	q.mu.Unlock()
+	if held == nil || held.Count != 20 {
+		t.Fatalf("held item = %+v, want count 20", held)
+	}
+	if got := p.plays.Load(); got != 0 {
+		t.Fatalf("plays during burst = %d, want 0", got)
+	}
+
+	// Once matches pause, the burst plays once
+	clock.Advance(40 * time.Millisecond)
+	q.Drain()
+	if got := p.plays.Load(); got != 1 {
+		t.Errorf("plays after pause = %d, want 1", got)
+	}
+}
+
+func TestDebounceWithCooldown(t *testing.T) {
+	q, p, clock := newTestQueue(t, &config.Queue{DebounceMs: 50, CooldownMs: 500})
+
+	// Two bursts separated by less than the cooldown play once
+	for burst := 0; burst < 2; burst++ {
+		for i := 0; i < 5; i++ {
+			q.Add("x")
+			clock.Advance(10 * time.Millisecond)
+		}
+		clock.Advance(100 * time.Millisecond)
+	}
+	q.Drain()
+
+	if got := p.plays.Load(); got != 1 {
+		t.Errorf("plays = %d, want 1", got)
+	}
+}
+
+func TestEscalate(t *testing.T) {
+	q, p, clock := newTestQueue(t, &config.Queue{
+		Escalate: &config.Escalation{Semitones: 2, MaxSteps: 3, ResetMs: 1000},
+	})
+
+	// Each repeat rises by two semitones up to the cap
+	for i := 0; i < 5; i++ {
+		q.Add("FAIL")
+		q.Drain()
+		clock.Advance(100 * time.Millisecond)
+	}
+
+	// A quiet period starts over at the base pitch
+	clock.Advance(time.Second)
+	q.Add("FAIL")
+	q.Drain()
+
+	want := []int{0, 2, 4, 6, 6, 0}
+	if got := p.semitones(440); !slices.Equal(got, want) {
+		t.Errorf("semitones = %v, want %v", got, want)
+	}
+}
+
+func TestEscalateCoalesced(t *testing.T) {
+	q, p, clock := newTestQueue(t, &config.Queue{
+		DebounceMs: 50,
+		Escalate:   &config.Escalation{Semitones: 1, MaxSteps: 10, ResetMs: 1000},
+	})
+
+	// A debounced burst of four plays at the step of its last match
+	for i := 0; i < 4; i++ {
+		q.Add("FAIL")
+	}
+	clock.Advance(50 * time.Millisecond)
+	q.Drain()
+
+	// The next match continues the burst
+	q.Add("FAIL")
+	clock.Advance(50 * time.Millisecond)
+	q.Drain()
+
+	want := []int{3, 4}
+	if got := p.semitones(440); !slices.Equal(got, want) {
+		t.Errorf("semitones = %v, want %v", got, want)
+	}
+}
+
+func TestCharacterMap(t *testing.T) {
+	click := &sample.SampleConfig{Name: "click", Frequency: 1000}
+	q, p, _ := newTestQueue(t, &config.Queue{
+		Map: []config.MapRule{
+			{Class: config.ClassLetter, Scale: "pentatonic", Octaves: 2},
+			{Chars: "!?", SampleName: "click", Sample: click},
+		},
+	})
+
+	// Letters walk up the scale and wrap after two octaves; the digit is
+	// covered by no rule and plays nothing
+	for _, text := range []string{"a", "B", "f", "j", "k", "7", "!"} {
+		q.Add(text)
+		q.Drain()
+	}
+
+	got := p.semitones(440)
+	if len(got) != 6 {
+		t.Fatalf("sounds = %v, want 6", got)
+	}
+	if want := []int{0, 2, 12, 21, 0}; !slices.Equal(got[:5], want) {
+		t.Errorf("letter semitones = %v, want %v", got[:5], want)
+	}
+	if got := p.semitones(1000); got[5] != 0 {
+		t.Errorf("sounds = %v, want the click sample last", got)
+	}
+}
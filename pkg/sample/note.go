@@ -0,0 +1,67 @@
+package sample
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// noteOffsets maps note letters to semitones above C.
+var noteOffsets = map[rune]int{
+	'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11,
+}
+
+// NoteFrequency returns the equal-tempered frequency of a note written in
+// scientific pitch notation: a letter A-G, any number of sharps ('#' or
+// '♯') or flats ('b' or '♭'), and an octave number, e.g. "A4", "C#5",
+// "Bb3" or "C-1". The tuning is the frequency of A4 in Hz; zero selects
+// DefaultTuning.
+func NoteFrequency(note string, tuning float64) (float64, error) {
+	semitones, err := NoteSemitones(note)
+	if err != nil {
+		return 0, err
+	}
+	if tuning == 0 {
+		tuning = DefaultTuning
+	}
+	return tuning * math.Pow(2, float64(semitones)/12), nil
+}
+
+// NoteSemitones returns the number of semitones between A4 and a note in
+// scientific pitch notation.
+func NoteSemitones(note string) (int, error) {
+	s := strings.TrimSpace(note)
+	letter, size := utf8.DecodeRuneInString(s)
+	offset, ok := noteOffsets[toUpper(letter)]
+	if !ok {
+		return 0, fmt.Errorf("invalid note %q: must start with a letter A-G", note)
+	}
+	s = s[size:]
+
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r == '#' || r == '♯' {
+			offset++
+		} else if r == 'b' || r == '♭' {
+			offset--
+		} else {
+			break
+		}
+		s = s[size:]
+	}
+
+	octave, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid note %q: missing or invalid octave", note)
+	}
+	return (octave-4)*12 + offset - noteOffsets['A'], nil
+}
+
+func toUpper(r rune) rune {
+	if r >= 'a' && r <= 'z' {
+		return r - 'a' + 'A'
+	}
+	return r
+}
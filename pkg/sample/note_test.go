@@ -0,0 +1,102 @@
+package sample
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNoteSemitones(t *testing.T) {
+	tests := []struct {
+		note string
+		want int
+	}{
+		{"A4", 0},
+		{"a4", 0},
+		{" A4 ", 0},
+		{"C4", -9},
+		{"C#5", 4},
+		{"C♯5", 4},
+		{"Bb3", -11},
+		{"B♭3", -11},
+		{"Cb4", -10},
+		{"C##4", -7},
+		{"A0", -48},
+		{"C-1", -69},
+		{"G9", 58},
+	}
+	for _, tt := range tests {
+		got, err := NoteSemitones(tt.note)
+		if err != nil {
+			t.Errorf("NoteSemitones(%q) = %v", tt.note, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NoteSemitones(%q) = %d, want %d", tt.note, got, tt.want)
+		}
+	}
+
+	for _, note := range []string{"", "H4", "4", "A", "A#", "Ax4", "A4.5", "♯A4"} {
+		if _, err := NoteSemitones(note); err == nil {
+			t.Errorf("NoteSemitones(%q) succeeded, want an error", note)
+		}
+	}
+}
+
+func TestNoteFrequency(t *testing.T) {
+	tests := []struct {
+		note   string
+		tuning float64
+		want   float64
+	}{
+		{"A4", 0, 440},
+		{"A4", 432, 432},
+		{"A5", 0, 880},
+		{"A3", 415, 207.5},
+		{"C4", 0, 261.6256},
+		{"C-1", 0, 8.1758},
+	}
+	for _, tt := range tests {
+		got, err := NoteFrequency(tt.note, tt.tuning)
+		if err != nil {
+			t.Errorf("NoteFrequency(%q, %v) = %v", tt.note, tt.tuning, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("NoteFrequency(%q, %v) = %.4f, want %.4f", tt.note, tt.tuning, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePitch(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      SampleConfig
+		tuning float64
+		want   float64
+	}{
+		{"frequency", SampleConfig{Frequency: 1000}, 0, 1000},
+		{"note", SampleConfig{Note: "A5"}, 0, 880},
+		{"note overrides frequency", SampleConfig{Note: "A4", Frequency: 1000}, 0, 440},
+		{"tuning", SampleConfig{Note: "A4"}, 432, 432},
+		{"cents up", SampleConfig{Note: "A4", Cents: 1200}, 0, 880},
+		{"cents down", SampleConfig{Frequency: 1000, Cents: -1200}, 0, 500},
+		{"semitone in cents", SampleConfig{Note: "A4", Cents: 100}, 0, 466.1638},
+	}
+	for _, tt := range tests {
+		s := tt.s
+		for i := 1; i <= 2; i++ {
+			if err := s.ResolvePitch(tt.tuning); err != nil {
+				t.Fatalf("%s: ResolvePitch() = %v", tt.name, err)
+			}
+			// A second call must not apply the cents again
+			if math.Abs(s.Frequency-tt.want) > 1e-3 {
+				t.Errorf("%s: frequency after call %d = %.4f, want %.4f", tt.name, i, s.Frequency, tt.want)
+			}
+		}
+	}
+
+	s := SampleConfig{Note: "H2"}
+	if err := s.ResolvePitch(0); err == nil {
+		t.Error("ResolvePitch with an invalid note succeeded, want an error")
+	}
+}
@@ -3,26 +3,220 @@ package sample
 import (
 	"errors"
 	"fmt"
+	"math"
+	"path/filepath"
+	"time"
+
+	"github.com/hiway/chirp/pkg/audio"
+)
+
+// DefaultTuning is the default frequency of A4 in Hz.
+const DefaultTuning = 440.0
+
+// Oscillator waveforms.
+const (
+	WaveSine       = "sine"
+	WaveSquare     = "square"
+	WaveTriangle   = "triangle"
+	WaveSawtooth   = "sawtooth"
+	WavePulse      = "pulse"
+	WaveWhiteNoise = "white_noise"
+	WavePinkNoise  = "pink_noise"
+)
+
+// Envelope curve shapes.
+const (
+	CurveLinear      = "linear"
+	CurveExponential = "exponential"
 )
 
+// Default envelope, as fractions of the sample duration for the times.
+const (
+	DefaultAttack  = 0.1
+	DefaultDecay   = 0.2
+	DefaultSustain = 0.7
+	DefaultRelease = 0.3
+)
+
+// DefaultPulseWidth is the default duty cycle of the pulse waveform.
+const DefaultPulseWidth = 0.25
+
 // SampleConfig defines the properties of an audio sample from the config file.
 // Renamed from Sample to SampleConfig to avoid confusion with the runtime Sample type.
 type SampleConfig struct {
 	Name      string  `toml:"-"`         // Name is derived from the map key in TOML
-	Duration  int     `toml:"duration"`  // Duration in milliseconds
-	Frequency int     `toml:"frequency"` // Frequency in Hz (TODO: Add support for musical notes)
+	Duration  int     `toml:"duration"`  // Duration in milliseconds; optional for files, where it limits playback
+	Frequency float64 `toml:"frequency"` // Frequency in Hz
+	Note      string  `toml:"note"`      // Musical note such as "A4", "C#5" or "Bb3"; overrides Frequency
+	Cents     float64 `toml:"cents"`     // Pitch offset in cents applied to Note or Frequency
 	Volume    float64 `toml:"volume"`    // Volume (0.0 to 1.0)
-	// TODO: Add FilePath string `toml:"file_path"` for custom WAV/OGG files
+	Waveform  string  `toml:"waveform"`  // Oscillator waveform (default sine)
+	Duty      float64 `toml:"duty"`      // Duty cycle of the pulse waveform (default 0.25)
+
+	// ADSR envelope; unset values default to a share of Duration
+	AttackMs     *float64 `toml:"attack_ms"`     // Time to rise to full volume
+	DecayMs      *float64 `toml:"decay_ms"`      // Time to fall to the sustain level
+	SustainLevel *float64 `toml:"sustain_level"` // Level held until the release (0.0 to 1.0)
+	ReleaseMs    *float64 `toml:"release_ms"`    // Time to fade out at the end of Duration
+	Curve        string   `toml:"curve"`         // Envelope curve shape: linear or exponential (default linear)
+	File         string   `toml:"file"`          // Sound file to play instead of a tone, relative to the config file
+
+	PCM   *audio.Buffer `toml:"-"` // Decoded File, loaded with LoadFile
+	Pitch float64       `toml:"-"` // Playback rate of PCM, set by Transposed (1.0 if unset)
+
+	baseFrequency float64 // Frequency as configured, before Cents
+}
+
+// LoadFile decodes the sample's sound file, if any, into PCM. A relative
+// path is resolved against dir.
+func (s *SampleConfig) LoadFile(dir string) error {
+	if s.File == "" {
+		return nil
+	}
+	path := s.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	buf, err := audio.LoadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to load sample file '%s': %w", s.File, err)
+	}
+	s.PCM = buf
+	return nil
+}
+
+// ResolvePitch sets Frequency from Note, if given, and applies Cents. The
+// tuning is the frequency of A4 in Hz; zero selects DefaultTuning. Calling
+// it again gives the same result: Cents always apply to the configured
+// pitch, not to the frequency a previous call set.
+func (s *SampleConfig) ResolvePitch(tuning float64) error {
+	if s.baseFrequency == 0 {
+		s.baseFrequency = s.Frequency
+	}
+	f := s.baseFrequency
+	if s.Note != "" {
+		var err error
+		if f, err = NoteFrequency(s.Note, tuning); err != nil {
+			return err
+		}
+	}
+	s.Frequency = f * math.Pow(2, s.Cents/1200)
+	return nil
+}
+
+// Length returns how long the sample plays: Duration if set, otherwise the
+// length of its sound file.
+func (s *SampleConfig) Length() time.Duration {
+	if s.Duration <= 0 && s.PCM != nil {
+		return time.Duration(float64(s.PCM.Duration()) / s.PitchFactor())
+	}
+	return time.Duration(s.Duration) * time.Millisecond
+}
+
+// PitchFactor returns the playback rate of the sound file, 1.0 if unset.
+func (s *SampleConfig) PitchFactor() float64 {
+	if s.Pitch <= 0 {
+		return 1
+	}
+	return s.Pitch
+}
+
+// Transposed returns a copy of the sample shifted by the given number of
+// semitones. Tones change frequency; sound files change playback rate.
+func (s *SampleConfig) Transposed(semitones float64) *SampleConfig {
+	t := *s
+	factor := math.Pow(2, semitones/12)
+	t.Frequency *= factor
+	if t.PCM != nil {
+		t.Pitch = s.PitchFactor() * factor
+	}
+	return &t
+}
+
+// Envelope is a resolved ADSR envelope. Times are in seconds.
+type Envelope struct {
+	Attack  float64
+	Decay   float64
+	Sustain float64
+	Release float64
+	Curve   string
+}
+
+// Envelope returns the sample's envelope, filling in unset values from the
+// defaults.
+func (s *SampleConfig) Envelope() Envelope {
+	total := s.Length().Seconds()
+	seconds := func(ms *float64, fraction float64) float64 {
+		if ms == nil {
+			return fraction * total
+		}
+		return *ms / 1000
+	}
+	env := Envelope{
+		Attack:  seconds(s.AttackMs, DefaultAttack),
+		Decay:   seconds(s.DecayMs, DefaultDecay),
+		Sustain: DefaultSustain,
+		Release: seconds(s.ReleaseMs, DefaultRelease),
+		Curve:   s.Curve,
+	}
+	if s.SustainLevel != nil {
+		env.Sustain = *s.SustainLevel
+	}
+	return env
+}
+
+// PulseWidth returns the duty cycle used by the pulse waveform.
+func (s *SampleConfig) PulseWidth() float64 {
+	if s.Duty == 0 {
+		return DefaultPulseWidth
+	}
+	return s.Duty
+}
+
+// IsNoise reports whether the sample's waveform is a noise source, which
+// has no pitch.
+func (s *SampleConfig) IsNoise() bool {
+	return s.Waveform == WaveWhiteNoise || s.Waveform == WavePinkNoise
 }
 
 // Validate checks if the sample configuration is valid.
 func (s *SampleConfig) Validate() error {
-	if s.Duration <= 0 {
-		return errors.New("sample duration must be positive")
+	if s.File != "" {
+		if s.Duration < 0 {
+			return errors.New("sample duration cannot be negative")
+		}
+	} else {
+		if s.Duration <= 0 {
+			return errors.New("sample duration must be positive")
+		}
+		if s.Frequency <= 0 && !s.IsNoise() {
+			return errors.New("sample frequency must be positive")
+		}
+	}
+	switch s.Waveform {
+	case "":
+		s.Waveform = WaveSine // Default to sine if not specified
+	case WaveSine, WaveSquare, WaveTriangle, WaveSawtooth, WavePulse, WaveWhiteNoise, WavePinkNoise:
+	default:
+		return fmt.Errorf("unknown waveform %q", s.Waveform)
+	}
+	if s.Duty < 0 || s.Duty >= 1 {
+		return fmt.Errorf("sample duty must be between 0.0 and 1.0, got %f", s.Duty)
+	}
+	for name, v := range map[string]*float64{"attack_ms": s.AttackMs, "decay_ms": s.DecayMs, "release_ms": s.ReleaseMs} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("sample %s cannot be negative", name)
+		}
+	}
+	if s.SustainLevel != nil && (*s.SustainLevel < 0 || *s.SustainLevel > 1) {
+		return fmt.Errorf("sample sustain_level must be between 0.0 and 1.0, got %f", *s.SustainLevel)
 	}
-	if s.Frequency <= 0 {
-		// TODO: Add validation for musical notes if implemented
-		return errors.New("sample frequency must be positive")
+	switch s.Curve {
+	case "":
+		s.Curve = CurveLinear // Default to linear if not specified
+	case CurveLinear, CurveExponential:
+	default:
+		return fmt.Errorf("unknown envelope curve %q", s.Curve)
 	}
 	if s.Volume < 0.0 || s.Volume > 1.0 {
 		return fmt.Errorf("sample volume must be between 0.0 and 1.0, got %f", s.Volume)
@@ -0,0 +1,42 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// ExitStatus describes how the wrapped command ended.
+type ExitStatus struct {
+	Code   int            // Exit code, or -1 if the command was killed by a signal
+	Signal syscall.Signal // Signal that killed the command, or 0
+}
+
+// exitStatusOf extracts the exit status from a finished process.
+func exitStatusOf(state *os.ProcessState) ExitStatus {
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return ExitStatus{Code: -1, Signal: ws.Signal()}
+	}
+	return ExitStatus{Code: state.ExitCode()}
+}
+
+// Signaled reports whether the command was killed by a signal.
+func (s ExitStatus) Signaled() bool {
+	return s.Signal != 0
+}
+
+// ShellCode returns the status the way a shell reports it in $?: the exit
+// code, or 128 plus the signal number if the command was killed.
+func (s ExitStatus) ShellCode() int {
+	if s.Signaled() {
+		return 128 + int(s.Signal)
+	}
+	return s.Code
+}
+
+func (s ExitStatus) String() string {
+	if s.Signaled() {
+		return fmt.Sprintf("killed by signal %d (%s)", int(s.Signal), s.Signal)
+	}
+	return fmt.Sprintf("exit code %d", s.Code)
+}
@@ -9,45 +9,66 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/creack/pty"
 	"github.com/rs/zerolog"
 	"golang.org/x/term"
 )
 
-// Terminal manages the pseudo-terminal (PTY) for the wrapped shell.
+// outputDrainTimeout bounds how long Wait waits for the last output after
+// the command exits, in case a background process keeps the PTY open.
+const outputDrainTimeout = 500 * time.Millisecond
+
+// eofChar is the PTY's end-of-file character, ^D.
+const eofChar = 0x04
+
+// stopKillTimeout is how long Stop waits after hanging up the command
+// before killing it.
+const stopKillTimeout = 2 * time.Second
+
+// Terminal manages the pseudo-terminal (PTY) for the wrapped command.
 type Terminal struct {
-	log       zerolog.Logger
-	shellPath string
-	ptyFile   *os.File
-	cmd       *exec.Cmd
-	stopOnce  sync.Once
-	stopChan  chan struct{}
-	stdin     io.Reader
-	stdout    io.Writer
+	log        zerolog.Logger
+	command    []string
+	ptyFile    *os.File
+	cmd        *exec.Cmd
+	oldState   *term.State // Terminal mode of stdin before Start
+	stopOnce   sync.Once
+	stopChan   chan struct{}
+	outputDone chan struct{}
+	exited     chan struct{} // Closed once Wait has reaped the command
+	stdin      io.Reader
+	stdout     io.Writer
 
 	// Callbacks for processing data
 	HandleInput  func(data []byte) error
 	HandleOutput func(data []byte) error
 }
 
-// NewTerminal creates a new Terminal instance.
-func NewTerminal(shellPath string, log zerolog.Logger, stdin io.Reader, stdout io.Writer) *Terminal {
+// NewTerminal creates a new Terminal instance that runs command, a program
+// path followed by its arguments, such as a shell.
+func NewTerminal(command []string, log zerolog.Logger, stdin io.Reader, stdout io.Writer) *Terminal {
 	return &Terminal{
-		log:       log.With().Str("component", "terminal").Logger(),
-		shellPath: shellPath,
-		stopChan:  make(chan struct{}),
-		stdin:     stdin,
-		stdout:    stdout,
+		log:        log.With().Str("component", "terminal").Logger(),
+		command:    command,
+		stopChan:   make(chan struct{}),
+		outputDone: make(chan struct{}),
+		exited:     make(chan struct{}),
+		stdin:      stdin,
+		stdout:     stdout,
 	}
 }
 
-// Start launches the shell in a PTY and begins I/O handling.
+// Start launches the command in a PTY and begins I/O handling.
 func (t *Terminal) Start() error {
-	t.log.Debug().Str("shell", t.shellPath).Msg("Starting terminal")
+	if len(t.command) == 0 {
+		return fmt.Errorf("no command to run")
+	}
+	t.log.Debug().Strs("command", t.command).Msg("Starting terminal")
 
 	// Create the command
-	t.cmd = exec.Command(t.shellPath)
+	t.cmd = exec.Command(t.command[0], t.command[1:]...)
 
 	// Start the command with a PTY
 	var err error
@@ -61,20 +82,17 @@ func (t *Terminal) Start() error {
 	// Handle window resizes
 	go t.handleResizes()
 
-	// Set stdin to raw mode
-	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
-	if err != nil {
-		// Attempt cleanup before returning error
-		t.ptyFile.Close()
-		t.log.Error().Err(err).Msg("Failed to set raw mode on stdin")
-		return fmt.Errorf("failed to set raw mode: %w", err)
+	// Set stdin to raw mode, unless it is not a terminal (e.g. in CI);
+	// Stop restores it
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		t.oldState, err = term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			// Attempt cleanup before returning error
+			t.ptyFile.Close()
+			t.log.Error().Err(err).Msg("Failed to set raw mode on stdin")
+			return fmt.Errorf("failed to set raw mode: %w", err)
+		}
 	}
-	// Ensure state is restored on exit
-	go func() {
-		<-t.stopChan // Wait for stop signal
-		term.Restore(int(os.Stdin.Fd()), oldState)
-		t.log.Debug().Msg("Restored terminal state")
-	}()
 
 	// Start I/O copying goroutines
 	go t.copyInput()
@@ -84,11 +102,22 @@ func (t *Terminal) Start() error {
 	return nil
 }
 
-// Stop signals the terminal and associated goroutines to shut down.
+// Stop signals the terminal and associated goroutines to shut down. A
+// command still running is hung up, and killed if it has not exited after
+// stopKillTimeout. The terminal mode of stdin is restored before Stop
+// returns, so the caller may exit straight away.
 func (t *Terminal) Stop() {
 	t.stopOnce.Do(func() {
 		t.log.Debug().Msg("Stopping terminal")
 		close(t.stopChan) // Signal goroutines to stop
+		if t.oldState != nil {
+			if err := term.Restore(int(os.Stdin.Fd()), t.oldState); err != nil {
+				t.log.Warn().Err(err).Msg("Failed to restore terminal state")
+			} else {
+				t.log.Debug().Msg("Restored terminal state")
+			}
+		}
+		t.hangup()
 		if t.ptyFile != nil {
 			t.ptyFile.Close() // Close the PTY file descriptor
 		}
@@ -96,20 +125,63 @@ func (t *Terminal) Stop() {
 	})
 }
 
-// Wait waits for the underlying shell command to exit.
-func (t *Terminal) Wait() error {
+// Wait waits for the underlying command to exit and for its remaining
+// output to be copied, and returns how the command ended.
+func (t *Terminal) Wait() (ExitStatus, error) {
 	if t.cmd == nil || t.cmd.Process == nil {
-		return fmt.Errorf("command not started or already finished")
+		return ExitStatus{}, fmt.Errorf("command not started or already finished")
 	}
 	waitResult, err := t.cmd.Process.Wait()
+	close(t.exited)
 	if err != nil {
-		t.log.Error().Err(err).Msg("Error waiting for shell command exit")
-		return fmt.Errorf("error waiting for command: %w", err)
+		t.log.Error().Err(err).Msg("Error waiting for command exit")
+		return ExitStatus{}, fmt.Errorf("error waiting for command: %w", err)
+	}
+	status := exitStatusOf(waitResult)
+	t.log.Debug().Stringer("status", status).Msg("Command exited")
+
+	// Let the output copier reach the end of the PTY so nothing the
+	// command printed last is lost
+	select {
+	case <-t.outputDone:
+	case <-t.stopChan:
+	case <-time.After(outputDrainTimeout):
+		t.log.Debug().Msg("Timed out waiting for remaining output")
 	}
-	t.log.Debug().Str("status", waitResult.String()).Msg("Shell process exited")
-	// Ensure Stop is called if Wait finishes first (e.g., shell exits normally)
+
+	// Ensure Stop is called if Wait finishes first (e.g., command exits normally)
 	t.Stop()
-	return nil
+	return status, nil
+}
+
+// hangup sends SIGHUP to the command's process group, which pty.Start
+// makes a new session, then SIGKILL if it is still running after
+// stopKillTimeout.
+func (t *Terminal) hangup() {
+	if t.cmd == nil || t.cmd.Process == nil {
+		return
+	}
+	select {
+	case <-t.exited:
+		return
+	default:
+	}
+
+	pgid := -t.cmd.Process.Pid
+	if err := syscall.Kill(pgid, syscall.SIGHUP); err != nil {
+		t.log.Debug().Err(err).Msg("Failed to hang up command")
+		return
+	}
+	go func() {
+		select {
+		case <-t.exited:
+		case <-time.After(stopKillTimeout):
+			t.log.Warn().Dur("timeout", stopKillTimeout).Msg("Command ignored hangup, killing it")
+			if err := syscall.Kill(pgid, syscall.SIGKILL); err != nil {
+				t.log.Debug().Err(err).Msg("Failed to kill command")
+			}
+		}
+	}()
 }
 
 // handleResizes listens for SIGWINCH and updates the PTY size.
@@ -140,6 +212,7 @@ func (t *Terminal) handleResizes() {
 // copyInput reads from stdin, calls HandleInput, and writes to the PTY.
 func (t *Terminal) copyInput() {
 	buf := make([]byte, 1024) // Buffer for reading stdin
+	atLineStart := true       // Whether the last byte written ended a line
 	for {
 		select {
 		case <-t.stopChan:
@@ -147,11 +220,23 @@ func (t *Terminal) copyInput() {
 			return
 		default:
 			n, err := t.stdin.Read(buf)
+			if err == io.EOF {
+				// Pass end of input on as ^D and let the command decide
+				// when to exit, so piped or empty stdin does not kill it.
+				// Mid-line, the first ^D only submits the partial line.
+				t.log.Debug().Msg("Stdin closed, sending EOF to command")
+				eof := []byte{eofChar}
+				if !atLineStart {
+					eof = append(eof, eofChar)
+				}
+				t.ptyFile.Write(eof)
+				return
+			}
 			if err != nil {
-				if err != io.EOF && !strings.Contains(err.Error(), "file descriptor closed") {
+				if !strings.Contains(err.Error(), "file descriptor closed") {
 					t.log.Error().Err(err).Msg("Stdin read error")
 				}
-				t.Stop() // Trigger shutdown on stdin error/EOF
+				t.Stop() // Trigger shutdown on stdin error
 				return
 			}
 			if n > 0 {
@@ -168,6 +253,8 @@ func (t *Terminal) copyInput() {
 					t.Stop() // Trigger shutdown on PTY write error
 					return
 				}
+				last := data[n-1]
+				atLineStart = last == '\n' || last == '\r'
 			}
 		}
 	}
@@ -175,6 +262,7 @@ func (t *Terminal) copyInput() {
 
 // copyOutput reads from the PTY, calls HandleOutput, and writes to stdout.
 func (t *Terminal) copyOutput() {
+	defer close(t.outputDone)
 	buf := make([]byte, 8192) // Buffer for reading PTY output
 	for {
 		select {
@@ -187,6 +275,15 @@ func (t *Terminal) copyOutput() {
 				// Check for EOF or common PTY close errors
 				if err == io.EOF || strings.Contains(err.Error(), "input/output error") || strings.Contains(err.Error(), "file descriptor closed") {
 					t.log.Debug().Err(err).Msg("PTY read loop finished normally")
+					// The command closes the terminal just before it
+					// exits; give it time to, rather than hang it up
+					select {
+					case <-t.exited:
+						return
+					case <-t.stopChan:
+						return
+					case <-time.After(stopKillTimeout):
+					}
 				} else {
 					t.log.Error().Err(err).Msg("PTY read error")
 				}